@@ -2,22 +2,43 @@ package diff
 
 import (
 	"bytes"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"math/cmplx"
+	"path"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"testing"
+	"time"
 	"unicode/utf8"
 	"unsafe"
 
 	"github.com/rogpeppe/go-internal/fmtsort"
 	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
 	"kr.dev/diff/internal/diffseq"
 )
 
 var (
-	reflectBytes  = reflect.TypeOf((*[]byte)(nil)).Elem()
-	reflectString = reflect.TypeOf((*string)(nil)).Elem()
-	reflectBool   = reflect.TypeOf(true)
+	reflectBytes   = reflect.TypeOf((*[]byte)(nil)).Elem()
+	reflectString  = reflect.TypeOf((*string)(nil)).Elem()
+	reflectStrings = reflect.TypeOf((*[]string)(nil)).Elem()
+	reflectBool    = reflect.TypeOf(true)
+
+	reflectStringer = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	reflectFileInfo = reflect.TypeOf((*fs.FileInfo)(nil)).Elem()
+	reflectImage    = reflect.TypeOf((*image.Image)(nil)).Elem()
+	reflectTime     = reflect.TypeOf((*time.Time)(nil)).Elem()
+
+	reflectMultiError = reflect.TypeOf((*multiError)(nil)).Elem()
 )
 
 var (
@@ -37,6 +58,29 @@ func Each(f func(format string, arg ...any) (int, error), a, b any, opt ...Optio
 	each(a, b, &c)
 }
 
+// Fprint compares values a and b, writing each difference to w.
+// It returns the number of bytes written and
+// the first write error encountered, if any.
+// By default, its conditions for equality are like reflect.DeepEqual.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func Fprint(w io.Writer, a, b any, opt ...Option) (n int, err error) {
+	f := func(format string, arg ...any) {
+		if err != nil {
+			return
+		}
+		var m int
+		m, err = fmt.Fprintf(w, format, arg...)
+		n += m
+	}
+	var c config
+	c.init(func() {}, f, opt...)
+	each(a, b, &c)
+	return n, err
+}
+
 // Log compares values a and b, printing each difference to its logger.
 // By default, its logger object is log.Default()
 // and its conditions for equality are like reflect.DeepEqual.
@@ -53,6 +97,7 @@ func Log(a, b any, opt ...Option) {
 		c.output.Output(d+2, fmt.Sprintf(format, arg...))
 	}
 	c.init(func() {}, f, opt...)
+	c.inLog = true
 	each(a, b, &c)
 }
 
@@ -71,12 +116,26 @@ func Test(h Helperer, f func(format string, arg ...any), got, want any, opt ...O
 	h.Helper()
 	var c config
 	c.init(h.Helper, f, opt...)
-	c.inTest = true
-	c.aLabel = "got"
-	c.bLabel = "want"
+	c.initTest()
 	each(got, want, &c)
 }
 
+// TB compares values got and want, reporting each difference
+// with tb.Errorf.
+// It is equivalent to Test(tb, tb.Errorf, got, want, opt...).
+func TB(tb testing.TB, got, want any, opt ...Option) {
+	tb.Helper()
+	Test(tb, tb.Errorf, got, want, opt...)
+}
+
+// TBFatal compares values got and want, reporting the first
+// difference with tb.Fatalf.
+// It is equivalent to Test(tb, tb.Fatalf, got, want, opt...).
+func TBFatal(tb testing.TB, got, want any, opt ...Option) {
+	tb.Helper()
+	Test(tb, tb.Fatalf, got, want, opt...)
+}
+
 // Helperer marks the caller as a helper function.
 // It is satisfied by *testing.T and *testing.B.
 type Helperer interface {
@@ -86,13 +145,28 @@ type Helperer interface {
 type config struct {
 	sink func(format string, a ...any)
 
+	// note receives messages about the comparison
+	// itself, such as GobEqual encoding errors.
+	// They are not differences, so unlike sink
+	// it isn't wrapped by Summarize or EmitUnifiedFull.
+	note func(format string, a ...any)
+
 	level level // verbosity
 
+	// shortDepth is the nesting depth beyond which
+	// the short format elides values as {...}.
+	shortDepth int
+
+	alwaysType bool           // show types in the short format
+	typeKinds  []reflect.Kind // show types of these kinds in the short format
+
 	// equalFuncs treats non-nil functions as equal.
 	// In the == operator, non-nil function values
 	// are never equal, so it is often useless to compare them.
 	equalFuncs bool
 
+	ignoreFuncs bool // treat all functions as equal, skip func fields
+
 	// xform transforms values of the given type before
 	// they are included in the diff tree.
 	// hashes, weights, and differences are computed
@@ -100,27 +174,236 @@ type config struct {
 	xform    map[reflect.Type]reflect.Value
 	showOrig bool // also diff untransformed values
 
+	// xformCache holds the results of transforms
+	// applied during a single run, keyed by the
+	// location and type of the original value.
+	xformCache map[visit]reflect.Value
+
+	// showOrigOnChange also diffs untransformed values,
+	// but only where the transformed values differ.
+	showOrigOnChange bool
+
+	// showOrigOnChangeType is like showOrigOnChange,
+	// but only for the transforms of the given types.
+	showOrigOnChangeType map[reflect.Type]bool
+
+	mapKeyString bool // show map keys in paths using String
+
+	promoteEmbedded bool // omit embedded field names in paths
+	jsonPointer     bool // write paths as JSON pointers, without the root type
+	noRootType      bool // write paths without the root type
+
+	// decimalLike compares values with methods
+	// Equal(T) bool and String() string using those methods.
+	decimalLike bool
+
+	// fileInfo compares fs.FileInfo values using their methods,
+	// with tolerance fileInfoTol for ModTime.
+	fileInfo    bool
+	fileInfoTol time.Duration
+
+	forceText bool // diff invalid UTF-8 as text
+
+	// jsonNumbers treats float64 and integer values
+	// in interfaces as equal if their values are equal.
+	// numericCross does the same for all predeclared
+	// integer and floating-point types.
+	jsonNumbers  bool
+	numericCross bool
+
+	complexTol float64 // max magnitude of difference for equal complex values
+	equalNaN   bool    // treat NaN as equal to NaN in floats and complex numbers
+	noiseFloor float64 // max magnitude of numbers treated as zero
+
+	nilEmptyEqual  bool // treat nil and empty maps and slices as equal
+	collapseRanges bool // emit replaced runs of elements as one range
+	detectMoves    bool // report removed elements equal to added ones as moved
+	structByName   bool // compare fields of different struct types by name
+	structTags     bool // compare struct types differing only in tags
+	typeChanges    bool // report values of different types as type changes
+	chanShape      bool // compare channels by direction, cap, and len
+	protoTime      bool // compare protobuf Timestamp and Duration as time types
+	mapValuesAsSet bool // compare map values as multisets, ignoring keys
+	mapAbsentZero  bool // treat absent map keys as holding the zero value
+	ptrSlice       bool // report nil transitions of pointer elements
+	containerLimit int  // max differing elements shown per slice or map
+
+	// onlyPaths, if non-nil, holds patterns for the only
+	// paths at which differences are emitted.
+	onlyPaths []string
+
+	diffMethod bool // use Diff methods to describe differences
+
+	fullCollapse bool // in EmitFull output, elide equal elements
+
+	// fullSeqContext, if positive, is the number of equal
+	// slice elements to show around each differing one
+	// in EmitFull output.
+	fullSeqContext int
+
+	numberPercent bool // show the relative change between numbers
+	timeZone      bool // report equal times in different zones
+	rawStrings    bool // show multi-line strings as raw string literals
+	slicePrefix   bool // ignore elements of a beyond the length of b
+	noSharing     bool // report pointers present in both a and b
+	explicitPtr   bool // always show & and the type for pointers
+
+	// textDecoder, if set, decodes strings and byte slices
+	// to text, in place of checking for valid UTF-8.
+	textDecoder func([]byte) (string, bool)
+
+	base64Text bool // decode base64 and PEM byte slices for text diffs
+	contextual bool // show short string diffs with common context
+
+	updateGolden bool // in Golden, write the golden file
+
+	shortTypeNames bool // omit package qualifiers from type names
+	oneof          bool // describe differing interface dynamic types as cases
+	hideAddrs      bool // don't show memory addresses
+	byteChars      bool // diff binary data byte by byte
+	customLabels   bool // aLabel and bLabel were set by WithLabels
+
+	// tableColumns, if non-nil, holds column names for
+	// comparing slices of slices as tables of rows.
+	// tableKey, if non-empty, names the key column.
+	tableColumns []string
+	tableKey     string
+
+	grpcStatus        bool // compare gRPC status errors by code and message
+	multiError        bool // compare joined errors by their messages
+	multiErrorOrdered bool // in the order they were joined
+
+	seqLimit   int    // max number of values Seq collects from an iterator
+	timeLayout string // layout for displaying times
+
+	// stringJSON, if non-nil, holds path patterns for strings
+	// to compare as JSON documents. An empty list matches all.
+	stringJSON []string
+
+	// patch, if non-nil, collects differences for Patch
+	// instead of emitting them.
+	patch *[]Assign
+
+	// numberBase is the base for showing integers,
+	// overridden for particular types by numberBases.
+	numberBase  int
+	numberBases map[reflect.Type]int
+
+	// tagKey, if set, is the struct tag key
+	// consulted for per-field comparison policy.
+	tagKey string
+
+	// image compares image.Image values pixel by pixel,
+	// with tolerance imageTol for each color channel.
+	image    bool
+	imageTol uint8
+
+	// noPtrShortcut disables treating pointers, maps, and
+	// slices as equal when they point to the same location.
+	noPtrShortcut bool
+
 	format map[reflect.Type]reflect.Value
 
+	// visWS makes whitespace visible in
+	// lines with whitespace-only changes.
+	visWS *strings.Replacer
+
+	textLineNums bool // show line numbers in text diffs
+	intraLine    bool // mark changed runes within changed lines
+	inlineLimit  int  // max number of inline text segments, if positive
+
+	shape bool // b is a shape with placeholders, for Shape
+
+	// onVisit, if set, is called for each leaf compared.
+	onVisit func(path string, equal bool)
+
+	// gob holds types to be compared by their gob encoding.
+	gob map[reflect.Type]bool
+
+	// equalFunc holds user-provided equality functions
+	// for values of the given type.
+	// Values it reports as equal are not compared further.
+	equalFunc map[reflect.Type]reflect.Value
+
+	// accessor holds accessor functions for values
+	// of the given type, whose results are compared
+	// in place of the values.
+	accessor map[reflect.Type]reflect.Value
+
+	// keyOrder holds less functions for map key types,
+	// for ordering map entries.
+	keyOrder map[reflect.Type]reflect.Value
+
+	// keyedSlice holds key functions for element types
+	// of slices and arrays whose elements are matched by key.
+	keyedSlice map[reflect.Type]reflect.Value
+
 	helper func()
 	output Outputter
 
+	summarize bool // emit the number of differences at the end
+
+	// groupByPrefix buffers differences in grouped
+	// to emit them grouped by common path prefixes.
+	groupByPrefix bool
+	grouped       *[]pathDiff
+
+	// rootTypeHook, if set, is called with rootType
+	// after each comparison.
+	rootTypeHook func(string)
+	reverse      bool // swap a and b, along with their labels
+	failFast     bool // stop after the first difference
+
 	inTest bool
+	inLog  bool
+
+	// logRecord, if set, receives each difference found
+	// by Log as structured data, in place of output.
+	// The short representations a and b are empty
+	// if the values are absent.
+	logRecord func(path, msg, a, b string)
+
 	aLabel string
 	bLabel string
+
+	addedLabel   string // marks elements present only in b
+	removedLabel string // marks elements present only in a
 }
 
 func (c *config) init(h func(), f func(format string, arg ...any), opt ...Option) {
 	c.sink = f
+	c.note = f
 	c.helper = h
 	c.xform = map[reflect.Type]reflect.Value{}
 	c.format = map[reflect.Type]reflect.Value{}
+	c.equalFunc = map[reflect.Type]reflect.Value{}
+	c.gob = map[reflect.Type]bool{}
+	c.accessor = map[reflect.Type]reflect.Value{}
+	c.keyOrder = map[reflect.Type]reflect.Value{}
+	c.keyedSlice = map[reflect.Type]reflect.Value{}
+	c.showOrigOnChangeType = map[reflect.Type]bool{}
+	c.numberBases = map[reflect.Type]int{}
 	c.aLabel = "a"
 	c.bLabel = "b"
+	c.addedLabel = "(added)"
+	c.removedLabel = "(removed)"
+	c.seqLimit = 10000
+	c.timeLayout = time.RFC3339Nano
 	defaultOpt.apply(c)
 	OptionList(opt...).apply(c)
 }
 
+// initTest prepares c for use by Test and similar
+// functions, labeling the values got and want
+// unless labels were set by WithLabels.
+func (c *config) initTest() {
+	c.inTest = true
+	if !c.customLabels {
+		c.aLabel = "got"
+		c.bLabel = "want"
+	}
+}
+
 type visit struct {
 	p unsafe.Pointer
 	t reflect.Type
@@ -132,8 +415,61 @@ type emitter struct {
 	path     []string
 	av, bv   reflect.Value
 
-	aSeen map[visit]visit
-	bSeen map[visit]visit
+	aSeen map[visit]seen
+	bSeen map[visit]seen
+
+	// For PromoteEmbedded, embedRoot is the outermost struct
+	// type containing the current embedded struct,
+	// embedIndex is the index sequence of the current embedded
+	// struct within embedRoot, and embedDepth is the number of
+	// embedded field names at the end of path.
+	embedRoot  reflect.Type
+	embedIndex []int
+	embedDepth int
+
+	// For Patch, origB is the value of b at the outermost
+	// pseudo-element in path, such as "(transformed)", and
+	// origLen is the length of path up to it, if hasOrig is set.
+	// Insert marks an element to be inserted into a slice.
+	origB   reflect.Value
+	origLen int
+	hasOrig bool
+	insert  bool
+}
+
+// seen records a value visited during a walk,
+// for detecting cycles.
+type seen struct {
+	other visit  // value visited in parallel on the other side
+	path  string // path where it was first visited
+}
+
+// pathString returns the path to the current value,
+// for display in a message.
+func (e *emitter) pathString() string {
+	if e.rootType == "" && len(e.path) == 0 {
+		return "the root"
+	}
+	return e.rootType + strings.Join(e.path, "")
+}
+
+// visit reports a compared leaf at the current path
+// to the OnVisit func, if any.
+func (e *emitter) visit(equal bool) {
+	if e.config.onVisit != nil {
+		e.config.onVisit(e.rootType+strings.Join(e.path, ""), equal)
+	}
+}
+
+// isLeafKind returns whether values of kind k
+// are compared as a whole, with no parts to walk.
+func isLeafKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Array, reflect.Struct, reflect.Interface,
+		reflect.Map, reflect.Ptr, reflect.Slice:
+		return false
+	}
+	return true
 }
 
 func (e *emitter) set(av, bv reflect.Value) {
@@ -142,9 +478,73 @@ func (e *emitter) set(av, bv reflect.Value) {
 }
 
 func (e *emitter) emitf(format string, arg ...any) {
+	e.config.helper()
+	if e.config.onlyPaths != nil && !matchAnyPath(e.config.onlyPaths, strings.Join(e.path, "")) {
+		return
+	}
+	if e.config.patch != nil {
+		a := e.assign()
+		p := *e.config.patch
+		if n := len(p); e.hasOrig && n > 0 && slices.Equal(p[n-1].Path, a.Path) {
+			return // another difference within the same value
+		}
+		*e.config.patch = append(p, a)
+		return
+	}
+	if e.config.inLog && e.config.logRecord != nil {
+		e.emitRecord(format, arg...)
+	} else {
+		e.emitSink(format, arg...)
+	}
+	if e.config.failFast {
+		panic(errStop)
+	}
+}
+
+// notef writes a message about the comparison at e's path.
+// Unlike emitf, it does not report a difference.
+func (e *emitter) notef(format string, arg ...any) {
+	e.config.helper()
+	if e.config.patch != nil {
+		return
+	}
+	var p string
+	if len(e.path) > 0 {
+		p = strings.Join(e.path, "") + ": "
+	}
+	arg = append([]any{e.rootType, p}, arg...)
+	e.config.note("%s%snote: "+format+"\n", arg...)
+}
+
+// emitRecord sends the difference to the config's
+// logRecord func, for SlogHandler.
+func (e *emitter) emitRecord(format string, arg ...any) {
+	e.config.helper()
+	var as, bs string
+	if e.av.IsValid() {
+		as = fmt.Sprint(e.short(e.av, false))
+	}
+	if e.bv.IsValid() {
+		bs = fmt.Sprint(e.short(e.bv, false))
+	}
+	msg := strings.TrimPrefix(fmt.Sprintf(format, arg...), "\n")
+	e.config.logRecord(e.rootType+strings.Join(e.path, ""), msg, as, bs)
+}
+
+// emitSink formats the difference according to the
+// verbosity level and writes it to the config's sink.
+func (e *emitter) emitSink(format string, arg ...any) {
 	e.config.helper()
 	switch e.config.level {
 	case auto:
+		if e.config.grouped != nil {
+			*e.config.grouped = append(*e.config.grouped, pathDiff{
+				rootType: e.rootType,
+				path:     append([]string(nil), e.path...),
+				msg:      fmt.Sprintf(format, arg...),
+			})
+			break
+		}
 		var p string
 		if len(e.path) > 0 {
 			p = strings.Join(e.path, "") + ": "
@@ -156,7 +556,14 @@ func (e *emitter) emitf(format string, arg ...any) {
 		e.config.sink("%s%s"+format+"\n", arg...)
 	case pathOnly:
 		e.config.sink("%s%s\n", e.rootType, strings.Join(e.path, ""))
-	case full:
+	case full, goLiteral, unifiedFull:
+		// eachWalk replaces unifiedFull with pathOnly
+		// before walking; should an emitter see it anyway,
+		// show the values in full for this difference.
+		ff := formatFull
+		if e.config.level == goLiteral {
+			ff = formatGo
+		}
 		var t string
 		if e.rootType != "" {
 			t = e.rootType + ":\n"
@@ -164,28 +571,252 @@ func (e *emitter) emitf(format string, arg ...any) {
 			t = "any:\n"
 		}
 		p := strings.Join(e.path, "")
+		af, bf := ff(e.av), ff(e.bv)
+		if (e.config.fullCollapse || e.config.fullSeqContext > 0) && e.config.level == full {
+			af = formatFullFocus(e.av, e.bv, e.config.usableEqual)
+			bf = formatFullFocus(e.bv, e.av, e.config.usableEqual)
+			for _, f := range []*formatter{af, bf} {
+				f.collapse = e.config.fullCollapse
+				f.seqContext = e.config.fullSeqContext
+			}
+		}
+		e.display(af)
+		e.display(bf)
 		e.config.sink("%s%s%s:\n%#v\n%s%s:\n%#v\n", t,
-			e.config.aLabel, p, formatFull(e.av),
-			e.config.bLabel, p, formatFull(e.bv),
+			e.config.aLabel, p, af,
+			e.config.bLabel, p, bf,
 		)
 	default:
 		panic("diff: bad verbose level")
 	}
 }
 
+// matchAnyPath returns whether path p matches any of patterns,
+// as described in OnlyPaths.
+func matchAnyPath(patterns []string, p string) bool {
+	for _, pat := range patterns {
+		if matchPath(pat, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns whether pattern pat matches p
+// or a prefix of p ending at a path element boundary,
+// including a pseudo-element such as "(transformed)".
+// In pat, * matches any sequence of characters
+// not containing '.', '[', or '('.
+func matchPath(pat, p string) bool {
+	for len(pat) > 0 {
+		if pat[0] == '*' {
+			pat = pat[1:]
+			for i := 0; ; i++ {
+				if matchPath(pat, p[i:]) {
+					return true
+				}
+				if i == len(p) || isPathBoundary(p[i]) {
+					return false
+				}
+			}
+		}
+		if p == "" || pat[0] != p[0] {
+			return false
+		}
+		pat, p = pat[1:], p[1:]
+	}
+	return p == "" || isPathBoundary(p[0])
+}
+
+// isPathBoundary returns whether c begins a path element,
+// including an element of a JSON pointer.
+func isPathBoundary(c byte) bool {
+	return c == '.' || c == '[' || c == '(' || c == '/'
+}
+
+// short returns a formatter for the short representation of v,
+// as used in the emitted "!=", added, and removed lines.
+func (e *emitter) short(v reflect.Value, wantType bool) *formatter {
+	if e.config.alwaysType || slices.Contains(e.config.typeKinds, v.Kind()) {
+		wantType = true
+	}
+	f := formatShort(v, wantType, e.config.shortDepth)
+	e.display(f)
+	return f
+}
+
+// typeName returns the name of t, for display in a message.
+func (e *emitter) typeName(t reflect.Type) string {
+	var buf bytes.Buffer
+	writeTypeBare(&buf, t, false, e.config.shortTypeNames)
+	return buf.String()
+}
+
+// display configures f with the options
+// that affect how values are displayed.
+func (e *emitter) display(f *formatter) {
+	f.rawStrings = e.config.rawStrings
+	f.explicitPtr = e.config.explicitPtr
+	f.bareTypes = e.config.shortTypeNames
+	f.hideAddrs = e.config.hideAddrs
+	f.timeLayout = e.config.timeLayout
+	f.keyOrder = e.config.keyOrder
+	f.numberBase = e.config.numberBase
+	f.numberBases = e.config.numberBases
+}
+
 func (e *emitter) subf(t reflect.Type, format string, arg ...any) *emitter {
-	if e.rootType == "" {
+	if e.rootType == "" && !e.config.jsonPointer && !e.config.noRootType {
 		var buf bytes.Buffer
-		writeType(&buf, t, false)
+		writeTypeBare(&buf, t, false, e.config.shortTypeNames)
 		e.rootType = buf.String()
 	}
-	return &emitter{
+	sub := &emitter{
 		config:   e.config,
 		rootType: e.rootType,
 		path:     append(e.path, fmt.Sprintf(format, arg...)),
 		aSeen:    e.aSeen,
 		bSeen:    e.bSeen,
+		origB:    e.origB,
+		origLen:  e.origLen,
+		hasOrig:  e.hasOrig,
+	}
+	if e.config.patch != nil && !e.hasOrig && strings.HasPrefix(format, "(") {
+		sub.origB, sub.origLen, sub.hasOrig = e.bv, len(e.path), true
+	}
+	return sub
+}
+
+// structByName compares the fields of structs av and bv,
+// which have different types, matching fields by name.
+func structByName(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	at, bt := av.Type(), bv.Type()
+	for i := 0; i < at.NumField(); i++ {
+		name := at.Field(i).Name
+		esub := e.subf(at, "."+name)
+		afield := access(av.Field(i))
+		if bf, ok := bt.FieldByName(name); ok && len(bf.Index) == 1 {
+			walk(esub, afield, access(bv.FieldByIndex(bf.Index)), true, false)
+		} else {
+			esub.set(afield, reflect.Value{})
+			esub.emitf("%s", esub.config.removedLabel)
+		}
+	}
+	for i := 0; i < bt.NumField(); i++ {
+		name := bt.Field(i).Name
+		if af, ok := at.FieldByName(name); ok && len(af.Index) == 1 {
+			continue
+		}
+		esub := e.subf(at, "."+name)
+		bfield := access(bv.Field(i))
+		esub.set(reflect.Value{}, bfield)
+		esub.emitf("%s %v", esub.config.addedLabel, esub.short(bfield, false))
+	}
+}
+
+// sameFieldsExceptTags returns whether at and bt are
+// struct types with the same name and the same fields,
+// except possibly for their tags.
+func sameFieldsExceptTags(at, bt reflect.Type) bool {
+	if at.Kind() != reflect.Struct || bt.Kind() != reflect.Struct ||
+		at.Name() != bt.Name() || at.NumField() != bt.NumField() {
+		return false
+	}
+	for i := 0; i < at.NumField(); i++ {
+		af, bf := at.Field(i), bt.Field(i)
+		if af.Name != bf.Name || af.PkgPath != bf.PkgPath ||
+			af.Type != bf.Type || af.Anonymous != bf.Anonymous {
+			return false
+		}
+	}
+	return true
+}
+
+// structTagDiff compares the fields of structs av and bv,
+// whose types differ only in their field tags,
+// emitting a difference for each differing tag.
+func structTagDiff(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	at, bt := av.Type(), bv.Type()
+	for i := 0; i < at.NumField(); i++ {
+		esub := e.field(at, i)
+		if atag, btag := at.Field(i).Tag, bt.Field(i).Tag; atag != btag {
+			esub.subf(at, "(tag)").emitf("%q != %q", atag, btag)
+		}
+		walk(esub, access(av.Field(i)), access(bv.Field(i)), true, false)
+	}
+}
+
+// index returns an emitter for element i
+// of a sequence of type t.
+func (e *emitter) index(t reflect.Type, i int) *emitter {
+	if e.config.jsonPointer {
+		return e.subf(t, "/%d", i)
+	}
+	return e.subf(t, "[%d]", i)
+}
+
+// key returns an emitter for the element with key k
+// in a map of type t.
+func (e *emitter) key(t reflect.Type, k reflect.Value) *emitter {
+	if e.config.jsonPointer && k.Kind() == reflect.String {
+		return e.subf(t, "/%s", jsonPointerEscaper.Replace(k.String()))
+	}
+	if e.config.mapKeyString && k.Type().Implements(reflectStringer) && k.CanInterface() {
+		return e.subf(t, "[%s]", k.Interface().(fmt.Stringer).String())
+	}
+	return e.subf(t, "[%#v]", k)
+}
+
+// field returns an emitter for field i of struct type t.
+func (e *emitter) field(t reflect.Type, i int) *emitter {
+	f := t.Field(i)
+	if !e.config.promoteEmbedded {
+		return e.subf(t, "."+f.Name)
+	}
+
+	root, index := t, []int{i}
+	if e.embedRoot != nil {
+		root = e.embedRoot
+		index = append(append(index[:0:0], e.embedIndex...), i)
+	}
+
+	esub := e.subf(t, "."+f.Name)
+	depth := e.embedDepth + 1
+	if rf, ok := root.FieldByName(f.Name); ok && slices.Equal(rf.Index, index) {
+		// The field is promoted; omit the embedded field names.
+		n := len(e.path) - e.embedDepth
+		esub.path = append(e.path[:n:n], "."+f.Name)
+		depth = 1
+	}
+
+	ft := f.Type
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	if f.Anonymous && ft.Kind() == reflect.Struct {
+		esub.embedRoot = root
+		esub.embedIndex = index
+		esub.embedDepth = depth
+	}
+	return esub
+}
+
+// transform applies transform func xf to v, which has type t.
+// Results for addressable values are cached for the rest of
+// the run, since the same value may be compared many times.
+func (e *emitter) transform(xf reflect.Value, t reflect.Type, v reflect.Value) reflect.Value {
+	if e.config.xformCache == nil || !v.CanAddr() {
+		return addressable(reflectApply(xf, v).Elem())
+	}
+	k := visit{unsafe.Pointer(v.UnsafeAddr()), t}
+	x, ok := e.config.xformCache[k]
+	if !ok {
+		x = addressable(reflectApply(xf, v).Elem())
+		e.config.xformCache[k] = x
 	}
+	return x
 }
 
 func reflectApply(f reflect.Value, v ...reflect.Value) reflect.Value {
@@ -193,14 +824,212 @@ func reflectApply(f reflect.Value, v ...reflect.Value) reflect.Value {
 }
 
 func each(a, b any, c *config) {
+	c.helper()
+	eachState(a, b, c, newRunState())
+}
+
+// A runState holds the per-run state of a comparison,
+// so it can be reused by a Differ.
+type runState struct {
+	aSeen, bSeen map[visit]seen
+	xformCache   map[visit]reflect.Value
+}
+
+func newRunState() *runState {
+	return &runState{
+		aSeen:      map[visit]seen{},
+		bSeen:      map[visit]seen{},
+		xformCache: map[visit]reflect.Value{},
+	}
+}
+
+// reset clears s for reuse.
+func (s *runState) reset() {
+	for k := range s.aSeen {
+		delete(s.aSeen, k)
+	}
+	for k := range s.bSeen {
+		delete(s.bSeen, k)
+	}
+	for k := range s.xformCache {
+		delete(s.xformCache, k)
+	}
+}
+
+// eachState is like each, but uses s for its per-run state.
+func eachState(a, b any, c *config, s *runState) {
+	c.helper()
+	eachWalk(a, b, c, s, walkRoot)
+}
+
+// eachWalk is like eachState, but calls w
+// in place of walk to compare the values.
+func eachWalk(a, b any, c *config, s *runState, w func(e *emitter, av, bv reflect.Value)) {
 	c.helper()
 	e := &emitter{
 		config: *c,
-		aSeen:  map[visit]visit{},
-		bSeen:  map[visit]visit{},
+		aSeen:  s.aSeen,
+		bSeen:  s.bSeen,
+	}
+	e.config.xformCache = s.xformCache
+	if c.reverse {
+		a, b = b, a
+		e.config.aLabel, e.config.bLabel = c.bLabel, c.aLabel
+	}
+	n := 0
+	if c.summarize {
+		sink := c.sink
+		e.config.sink = func(format string, arg ...any) {
+			c.helper()
+			n++
+			sink(format, arg...)
+		}
+		if rec := c.logRecord; rec != nil {
+			e.config.logRecord = func(path, msg, a, b string) {
+				n++
+				rec(path, msg, a, b)
+			}
+		}
+	}
+	var grouped []pathDiff
+	if c.groupByPrefix && c.level == auto {
+		e.config.grouped = &grouped
+	}
+	nUnified := 0
+	sink := e.config.sink
+	if c.level == unifiedFull {
+		// Only count differences here;
+		// the whole values are diffed below.
+		e.config.level = pathOnly
+		e.config.sink = func(string, ...any) { nUnified++ }
 	}
 	av := addressable(reflect.ValueOf(a))
 	bv := addressable(reflect.ValueOf(b))
+	walkTop(e, av, bv, w)
+	if c.rootTypeHook != nil {
+		c.rootTypeHook(e.rootType)
+	}
+	if grouped != nil {
+		var header string
+		emitGrouped(e.config.sink, grouped, grouped[0].rootType, "", &header)
+	}
+	if nUnified > 0 {
+		e.config.sink = sink
+		emitUnified(e, av, bv)
+		if c.summarize {
+			n = nUnified
+		}
+	}
+	if n == 1 {
+		c.sink("# 1 difference\n")
+	} else if n > 1 {
+		c.sink("# %d differences\n", n)
+	}
+}
+
+// emitUnified emits a line diff of the full
+// representations of av and bv, for EmitUnifiedFull.
+func emitUnified(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	af, bf := formatFull(av), formatFull(bv)
+	e.display(af)
+	e.display(bf)
+	var t string
+	if e.rootType != "" {
+		t = e.rootType + ":\n"
+	} else if e.config.inTest {
+		t = "any:\n"
+	}
+	e.config.sink("%s%s", t, &diffTextFormatter{
+		a:         fmt.Sprintf("%#v", af),
+		b:         fmt.Sprintf("%#v", bf),
+		aLabel:    e.config.aLabel,
+		bLabel:    e.config.bLabel,
+		visWS:     e.config.visWS,
+		lineNums:  e.config.textLineNums,
+		intraLine: e.config.intraLine,
+	})
+}
+
+// A pathDiff is a difference buffered for GroupByPrefix.
+type pathDiff struct {
+	rootType string
+	path     []string
+	msg      string
+}
+
+// emitGrouped emits ds, grouping runs of differences
+// that share leading path elements under a header line
+// showing those elements, with the rest indented below it.
+// Each call to sink emits one difference, preceded by any
+// pending header lines accumulated in *header.
+func emitGrouped(sink func(format string, arg ...any), ds []pathDiff, prefix, indent string, header *string) {
+	for i := 0; i < len(ds); {
+		j := i + 1
+		if len(ds[i].path) > 0 {
+			for j < len(ds) && len(ds[j].path) > 0 && ds[j].path[0] == ds[i].path[0] {
+				j++
+			}
+		}
+		group := ds[i:j]
+		i = j
+		if k := commonPathLen(group); len(group) > 1 && k > 0 {
+			*header += indent + prefix + strings.Join(group[0].path[:k], "") + ":\n"
+			sub := make([]pathDiff, len(group))
+			for m, d := range group {
+				sub[m] = pathDiff{path: d.path[k:], msg: d.msg}
+			}
+			emitGrouped(sink, sub, "", indent+tab, header)
+			continue
+		}
+		for _, d := range group {
+			msg := strings.ReplaceAll(d.msg, "\n", "\n"+indent)
+			if p := prefix + strings.Join(d.path, ""); p != "" {
+				msg = p + ": " + msg
+			} else {
+				msg = strings.TrimPrefix(msg, "\n")
+			}
+			sink("%s%s%s\n", *header, indent, msg)
+			*header = ""
+		}
+	}
+}
+
+// commonPathLen returns the number of leading path
+// elements shared by all of ds, leaving at least one
+// element in each.
+func commonPathLen(ds []pathDiff) int {
+	k := len(ds[0].path) - 1
+	for _, d := range ds {
+		k = min(k, len(d.path)-1)
+	}
+	for m := 0; m < k; m++ {
+		for _, d := range ds {
+			if d.path[m] != ds[0].path[m] {
+				return m
+			}
+		}
+	}
+	return k
+}
+
+// errStop is panicked to stop walking early, for FailFast.
+var errStop = errors.New("diff: stop")
+
+// walkTop calls w to walk av and bv, recovering from errStop.
+func walkTop(e *emitter, av, bv reflect.Value, w func(e *emitter, av, bv reflect.Value)) {
+	e.config.helper()
+	defer func() {
+		if r := recover(); r != nil && r != errStop {
+			panic(r)
+		}
+	}()
+	w(e, av, bv)
+}
+
+// walkRoot walks av and bv as the root values.
+func walkRoot(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
 	walk(e, av, bv, true, true)
 }
 
@@ -208,29 +1037,97 @@ func equal(av, bv reflect.Value, c *config, xformOk bool) bool {
 	var n int
 	e := &emitter{
 		config: *c,
-		aSeen:  map[visit]visit{},
-		bSeen:  map[visit]visit{},
+		aSeen:  map[visit]seen{},
+		bSeen:  map[visit]seen{},
 	}
 	e.config.format = nil
+	e.config.failFast = false
+	e.config.onlyPaths = nil
+	e.config.grouped = nil
+	e.config.patch = nil
+	e.config.onVisit = nil
+	e.config.logRecord = nil
+	e.config.note = func(string, ...any) {}
+	e.config.noRootType = true // not shown, so don't compute it
 	e.config.sink = func(string, ...any) { n++ }
 	walk(e, av, bv, xformOk, true)
 	return n == 0
 }
 
+// usableEqual is like equal, but accepts values obtained
+// through unexported fields. It reports false if it can't
+// compare a and b.
+func (c *config) usableEqual(a, b reflect.Value) bool {
+	var ok bool
+	if a, ok = usable(a); !ok {
+		return false
+	}
+	if b, ok = usable(b); !ok {
+		return false
+	}
+	return equal(a, b, c, true)
+}
+
+// usable returns a copy of v that can be used
+// without restriction, if possible.
+func usable(v reflect.Value) (reflect.Value, bool) {
+	if v.CanAddr() {
+		return access(v), true
+	}
+	if v.CanInterface() {
+		return addressable(v), true
+	}
+	return v, false
+}
+
 func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 	e.config.helper()
 	e.set(av, bv)
+	if e.config.shape && bv.IsValid() && bv.Type() == shapeLeafType {
+		shapeLeafDiff(e, av, bv)
+		return
+	}
 	if !av.IsValid() && !bv.IsValid() {
 		return
 	}
 	if !av.IsValid() || !bv.IsValid() {
-		e.emitf("%v != %v", formatShort(av, true), formatShort(bv, true))
+		e.emitf("%v != %v", e.short(av, true), e.short(bv, true))
 		return
 	}
 
 	t := av.Type()
+	if t != bv.Type() && e.config.structByName &&
+		t.Kind() == reflect.Struct && bv.Kind() == reflect.Struct {
+		structByName(e, av, bv)
+		return
+	}
+	if t != bv.Type() && e.config.structTags && sameFieldsExceptTags(t, bv.Type()) {
+		structTagDiff(e, av, bv)
+		return
+	}
+	if t != bv.Type() && e.config.chanShape && t.Kind() == reflect.Chan &&
+		bv.Kind() == reflect.Chan && t.Elem() == bv.Type().Elem() {
+		chanShapeDiff(e, av, bv)
+		return
+	}
+	if t != bv.Type() && e.config.typeChanges {
+		an, bn := e.typeName(t), e.typeName(bv.Type())
+		if an == bn {
+			// Qualify the names, to tell the types apart.
+			an, bn = t.String(), bv.Type().String()
+		}
+		e.emitf("type %s != %s (values: %v, %v)",
+			an, bn, e.short(av, false), e.short(bv, false))
+		return
+	}
 	if t != bv.Type() {
-		e.emitf("%v != %v", formatShort(av, true), formatShort(bv, true))
+		af, bf := e.short(av, true), e.short(bv, true)
+		if e.config.shortTypeNames && bareTypeName(t) == bareTypeName(bv.Type()) {
+			// Qualify the names, to tell the types apart.
+			af.bareTypes = false
+			bf.bareTypes = false
+		}
+		e.emitf("%v != %v", af, bf)
 		return
 	}
 
@@ -242,44 +1139,170 @@ func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 		}
 		avis := visit{unsafe.Pointer(av.Pointer()), t}
 		bvis := visit{unsafe.Pointer(bv.Pointer()), t}
-		if bSeen, ok := e.aSeen[avis]; ok {
-			if bSeen != bvis {
-				e.emitf("uneven cycle")
+		if as, ok := e.aSeen[avis]; ok {
+			if as.other != bvis {
+				if bs, ok := e.bSeen[bvis]; ok {
+					e.emitf("uneven cycle: %s revisits %s, %s revisits %s",
+						e.config.aLabel, as.path, e.config.bLabel, bs.path)
+				} else {
+					e.emitf("uneven cycle: %s revisits %s, %s does not",
+						e.config.aLabel, as.path, e.config.bLabel)
+				}
 			}
 			return
 		}
-		if _, ok := e.bSeen[bvis]; ok {
-			e.emitf("uneven cycle")
+		if bs, ok := e.bSeen[bvis]; ok {
+			e.emitf("uneven cycle: %s revisits %s, %s does not",
+				e.config.bLabel, bs.path, e.config.aLabel)
 			return
 		}
-		e.aSeen[avis] = bvis
-		e.bSeen[bvis] = avis
+		p := e.pathString()
+		e.aSeen[avis] = seen{bvis, p}
+		e.bSeen[bvis] = seen{avis, p}
+		if e.config.noSharing && (t.Kind() != reflect.Slice || av.Len() > 0 && bv.Len() > 0) {
+			_, aShared := e.aSeen[bvis]
+			_, bShared := e.bSeen[avis]
+			if aShared || bShared {
+				e.emitf("(shared pointer)")
+				return
+			}
+		}
 	}
 
-	// Check for a transform func.
-	if xf, haveXform := e.config.xform[t]; xformOk && haveXform {
-		ax := addressable(reflectApply(xf, av).Elem())
-		bx := addressable(reflectApply(xf, bv).Elem())
-		walk(e.subf(t, "(transformed)"), ax, bx, false, true)
-		if !e.config.showOrig {
+	// Check for an equal func.
+	if eq, ok := e.config.equalFunc[t]; ok {
+		ok := reflectApply(eq, av, bv).Bool()
+		e.visit(ok)
+		if ok {
 			return
 		}
-		e = e.subf(t, "(original)")
-		if equal(av, bv, &e.config, false) {
-			e.emitf("equal")
+		if ff, ok := e.config.format[t]; ok {
+			e.emitf("%s", reflectApply(ff, av, bv).String())
+		} else {
+			e.emitf("%v != %v", e.short(av, wantType), e.short(bv, wantType))
+		}
+		return
+	}
+
+	// Check for an accessor func.
+	if get, ok := e.config.accessor[t]; ok && xformOk {
+		ax := addressable(reflectApply(get, av).Elem())
+		bx := addressable(reflectApply(get, bv).Elem())
+		if ax.IsValid() && bx.IsValid() && ax.Type() == t && bx.Type() == t {
+			// Compare the results by their contents, below.
+			av, bv = ax, bx
+		} else {
+			walk(e, ax, bx, true, wantType)
+			return
+		}
+	}
+
+	// Check for decimal-like methods.
+	if e.config.decimalLike && isDecimalLike(t) {
+		eq := av.MethodByName("Equal").Call([]reflect.Value{bv})[0]
+		if !eq.Bool() {
+			as := av.MethodByName("String").Call(nil)[0]
+			bs := bv.MethodByName("String").Call(nil)[0]
+			e.emitf("%s != %s", as, bs)
+		}
+		return
+	}
+
+	// Check for fs.FileInfo.
+	if e.config.fileInfo && t.Implements(reflectFileInfo) && !isNil(av) && !isNil(bv) {
+		fileInfoDiff(e, t, av.Interface().(fs.FileInfo), bv.Interface().(fs.FileInfo))
+		return
+	}
+
+	// Check for gob encoding comparison.
+	if xformOk && e.config.gob[t] && gobDiff(e, t, av, bv) {
+		return
+	}
+
+	// Check for image.Image.
+	if e.config.image && t.Implements(reflectImage) && !isNil(av) && !isNil(bv) {
+		imageDiff(e, av.Interface().(image.Image), bv.Interface().(image.Image))
+		return
+	}
+
+	// Check for errors joined by errors.Join and the like.
+	if e.config.multiError && t.Implements(reflectMultiError) && !isNil(av) && !isNil(bv) {
+		multiErrorDiff(e, t, av.Interface().(multiError), bv.Interface().(multiError))
+		return
+	}
+
+	// Check for gRPC status errors.
+	if e.config.grpcStatus && hasGRPCStatus(t) && !isNil(av) && !isNil(bv) {
+		if au, ok := usable(av); ok {
+			if bu, ok := usable(bv); ok {
+				grpcStatusDiff(e, t, au, bu)
+				return
+			}
+		}
+	}
+
+	// Check for time instants in different zones.
+	if e.config.timeZone && t == reflectTime {
+		at, bt := av.Interface().(time.Time), bv.Interface().(time.Time)
+		if at.Equal(bt) {
+			if az, bz := at.Location().String(), bt.Location().String(); az != bz {
+				e.emitf("(zones differ: %s vs %s)", az, bz)
+			}
+			return
+		}
+	}
+
+	// Check for protobuf Timestamp and Duration.
+	if xformOk && e.config.protoTime {
+		if conv := protoTimeConv(t); conv != nil {
+			walk(e.subf(t, "(transformed)"), addressable(conv(av)), addressable(conv(bv)), false, true)
+			return
+		}
+	}
+
+	// Check for a transform func.
+	if xf, haveXform := e.config.xform[t]; xformOk && haveXform {
+		ax := e.transform(xf, t, av)
+		bx := e.transform(xf, t, bv)
+		walk(e.subf(t, "(transformed)"), ax, bx, false, true)
+		onChange := e.config.showOrigOnChange || e.config.showOrigOnChangeType[t]
+		if !e.config.showOrig && !onChange {
+			return
+		}
+		if onChange && equal(ax, bx, &e.config, false) {
+			return
+		}
+		e = e.subf(t, "(original)")
+		e.set(av, bv)
+		if equal(av, bv, &e.config, false) {
+			if !onChange {
+				e.emitf("equal")
+			}
 			return
 		}
 	}
 
 	// Check for a format func.
 	if ff, ok := e.config.format[t]; ok {
-		if !equal(av, bv, &e.config, false) {
+		eq := equal(av, bv, &e.config, false)
+		e.visit(eq)
+		if !eq {
 			s := reflectApply(ff, av, bv).String()
 			e.emitf("%s", s)
 		}
 		return
 	}
 
+	// Check for a Diff method.
+	if e.config.diffMethod && hasDiffMethod(t) {
+		diffMethod(e, t, av, bv)
+		return
+	}
+
+	if e.config.onVisit != nil && isLeafKind(t.Kind()) {
+		e.visit(equal(av, bv, &e.config, false))
+	}
+
 	// We use almost the same rules as reflect.DeepEqual here,
 	// but with a couple of configuration options that modify
 	// the behavior, such as:
@@ -290,12 +1313,29 @@ func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 		seqDiff(e, av, bv)
 	case reflect.Struct:
 		for i := 0; i < t.NumField(); i++ {
+			if e.config.ignoreFuncs && t.Field(i).Type.Kind() == reflect.Func {
+				continue
+			}
 			afield := access(av.Field(i))
 			bfield := access(bv.Field(i))
-			walk(e.subf(t, "."+t.Field(i).Name), afield, bfield, true, false)
+			if e.config.tagKey != "" {
+				ignore, unordered := fieldTag(t.Field(i), e.config.tagKey)
+				if ignore {
+					continue
+				}
+				k := afield.Kind()
+				if unordered && (k == reflect.Slice || k == reflect.Array) {
+					unorderedDiff(e.field(t, i), afield, bfield)
+					continue
+				}
+			}
+			walk(e.field(t, i), afield, bfield, true, false)
 		}
 	case reflect.Func:
-		if e.config.equalFuncs {
+		if e.config.ignoreFuncs {
+			break
+		}
+		if e.config.equalFuncs && av.IsNil() == bv.IsNil() {
 			break
 		}
 		if !av.IsNil() || !bv.IsNil() {
@@ -304,52 +1344,105 @@ func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 	case reflect.Interface:
 		aelem := addressable(av.Elem())
 		belem := addressable(bv.Elem())
+		if e.config.jsonNumbers && (jsonNumberEqual(aelem, belem) || jsonNumberEqual(belem, aelem)) {
+			break
+		}
+		if e.config.numericCross && numericCrossEqual(aelem, belem) {
+			break
+		}
+		isLeaf := e.config.shape && belem.IsValid() && belem.Type() == shapeLeafType
+		if e.config.oneof && !isLeaf && aelem.IsValid() && belem.IsValid() && aelem.Type() != belem.Type() {
+			e.emitf("%s has case %s, %s has case %s",
+				e.config.aLabel, e.typeName(aelem.Type()),
+				e.config.bLabel, e.typeName(belem.Type()),
+			)
+			break
+		}
 		walk(e, aelem, belem, xformOk, true)
 	case reflect.Map:
-		if av.IsNil() != bv.IsNil() {
+		if av.IsNil() != bv.IsNil() && !nilEmpty(av, bv, &e.config) && !absentZero(av, bv, &e.config) {
 			emitPointers(e, av, bv, wantType)
 			break
 		}
-		if av.Pointer() == bv.Pointer() {
+		if !e.config.noPtrShortcut && av.Pointer() == bv.Pointer() {
 			break
 		}
 
-		for _, k := range sortedKeys(av, bv) {
-			esub := e.subf(t, "[%#v]", k)
+		if e.config.mapValuesAsSet {
+			mapValueSetDiff(e, av, bv)
+			break
+		}
+		lim := e.limiter()
+		for _, k := range orderedKeys(e.config.keyOrder, av, bv) {
+			esub := e.key(t, k)
 			ak := addressable(av.MapIndex(k))
 			bk := addressable(bv.MapIndex(k))
 			esub.set(ak, bk)
+			if e.config.shape && !bk.IsValid() && ak.IsValid() {
+				continue // not in the shape
+			}
+			if lim.limit > 0 {
+				if ak.IsValid() && bk.IsValid() && equal(ak, bk, &e.config, true) {
+					continue
+				}
+				if !absentZeroEntry(ak, bk, &e.config) && !lim.allow() {
+					continue
+				}
+			}
+			if !ak.IsValid() && !bk.IsValid() {
+				// A key that isn't equal to itself, such as NaN,
+				// can't be used to look up its own element,
+				// so there's no way to pair it with the other map.
+				esub.emitf("(uncomparable map key)")
+				continue
+			}
 			if ak.IsValid() && bk.IsValid() {
 				walk(esub, ak, bk, true, false)
+			} else if absentZeroEntry(ak, bk, &e.config) {
+				continue
 			} else if ak.IsValid() {
-				esub.emitf("(removed)")
+				esub.emitf("%s", esub.config.removedLabel)
+			} else if e.config.shape {
+				esub.emitf("(missing)")
 			} else { // k in bv
-				esub.emitf("(added) %v", formatShort(bk, false))
+				esub.emitf("%s %v", esub.config.addedLabel, esub.short(bk, false))
 			}
 		}
+		lim.emitMore(e, av, bv)
 	case reflect.Ptr:
-		if av.Pointer() == bv.Pointer() {
+		if !e.config.noPtrShortcut && av.Pointer() == bv.Pointer() {
 			break
 		}
 		if av.IsNil() != bv.IsNil() {
-			e.emitf("%v != %v", formatShort(av, wantType), formatShort(bv, wantType))
+			e.emitf("%v != %v", e.short(av, wantType), e.short(bv, wantType))
 			break
 		}
 		walk(e, av.Elem(), bv.Elem(), true, wantType)
 	case reflect.Slice:
-		if av.IsNil() != bv.IsNil() {
+		if av.IsNil() != bv.IsNil() && !nilEmpty(av, bv, &e.config) {
 			emitPointers(e, av, bv, wantType)
 			break
 		}
-		if av.Len() == bv.Len() && av.Pointer() == bv.Pointer() {
+		if !e.config.noPtrShortcut && av.Len() == bv.Len() && av.Pointer() == bv.Pointer() {
 			break
 		}
+		if e.config.slicePrefix && av.Len() > bv.Len() {
+			av = av.Slice(0, bv.Len())
+		}
 		if t.ConvertibleTo(reflectBytes) {
 			as := av.Convert(reflectString)
 			bs := bv.Convert(reflectString)
+			if e.config.base64Text {
+				base64TextDiff(e, as.String(), bs.String())
+				break
+			}
 			stringDiff(e, t, as.String(), bs.String())
 			break
 		}
+		if e.config.tableColumns != nil && t.Elem().Kind() == reflect.Slice {
+			tableDiff(e, av, bv)
+			break
+		}
 		seqDiff(e, av, bv)
 	case reflect.Bool:
 		eqtest(e, av, bv, av.Bool(), bv.Bool(), wantType)
@@ -360,12 +1453,29 @@ func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		eqtest(e, av, bv, av.Uint(), bv.Uint(), wantType)
 	case reflect.Float32, reflect.Float64:
+		if e.config.equalNaN && floatEqualNaN(av.Float(), bv.Float()) {
+			break
+		}
 		eqtest(e, av, bv, av.Float(), bv.Float(), wantType)
 	case reflect.Complex64, reflect.Complex128:
+		if e.config.equalNaN && complexEqualNaN(av.Complex(), bv.Complex()) {
+			break
+		}
+		if e.config.complexTol > 0 {
+			complexDiff(e, av, bv, wantType)
+			break
+		}
 		eqtest(e, av, bv, av.Complex(), bv.Complex(), wantType)
 	case reflect.String:
+		if e.config.stringJSON != nil && stringJSONDiff(e, t, av.String(), bv.String()) {
+			break
+		}
 		stringDiff(e, t, av.String(), bv.String())
 	case reflect.Chan, reflect.UnsafePointer:
+		if t.Kind() == reflect.Chan && e.config.chanShape {
+			chanShapeDiff(e, av, bv)
+			break
+		}
 		if a, b := av.Pointer(), bv.Pointer(); a != b {
 			emitPointers(e, av, bv, wantType)
 		}
@@ -374,21 +1484,554 @@ func walk(e *emitter, av, bv reflect.Value, xformOk, wantType bool) {
 	}
 }
 
+// chanShapeDiff compares channels av and bv by their
+// direction, capacity, and number of queued elements,
+// for ChannelShape. It doesn't receive from them.
+func chanShapeDiff(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	if ad, bd := av.Type().ChanDir(), bv.Type().ChanDir(); ad != bd {
+		e.emitf("dir %s != %s", ad, bd)
+	}
+	if av.IsNil() != bv.IsNil() {
+		if av.IsNil() {
+			e.emitf("nil != non-nil")
+		} else {
+			e.emitf("non-nil != nil")
+		}
+		return
+	}
+	if ac, bc := av.Cap(), bv.Cap(); ac != bc {
+		e.emitf("cap %d != %d", ac, bc)
+	}
+	if al, bl := av.Len(), bv.Len(); al != bl {
+		e.emitf("len %d != %d", al, bl)
+	}
+}
+
+// isDecimalLike returns whether t has methods
+// Equal(t) bool and String() string.
+func isDecimalLike(t reflect.Type) bool {
+	eq, ok := t.MethodByName("Equal")
+	if !ok {
+		return false
+	}
+	str, ok := t.MethodByName("String")
+	if !ok {
+		return false
+	}
+	et, st := eq.Type, str.Type
+	return et.NumIn() == 2 && et.In(1) == t &&
+		et.NumOut() == 1 && et.Out(0) == reflectBool &&
+		st.NumIn() == 1 && st.NumOut() == 1 && st.Out(0) == reflectString
+}
+
+// hasDiffMethod returns whether t has a method
+// Diff(t) []string or Diff(t) string.
+func hasDiffMethod(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	m, ok := t.MethodByName("Diff")
+	if !ok {
+		return false
+	}
+	mt := m.Type
+	return mt.NumIn() == 2 && mt.In(1) == t && mt.NumOut() == 1 &&
+		(mt.Out(0) == reflectString || mt.Out(0) == reflectStrings)
+}
+
+// diffMethod emits the differences between av and bv
+// reported by their type's Diff method.
+func diffMethod(e *emitter, t reflect.Type, av, bv reflect.Value) {
+	e.config.helper()
+	if eq, ok := t.MethodByName("Equal"); ok {
+		et := eq.Type
+		if et.NumIn() == 2 && et.In(1) == t && et.NumOut() == 1 && et.Out(0) == reflectBool {
+			if av.MethodByName("Equal").Call([]reflect.Value{bv})[0].Bool() {
+				return
+			}
+		}
+	}
+	d := av.MethodByName("Diff").Call([]reflect.Value{bv})[0]
+	if d.Kind() == reflect.String {
+		if d.Len() > 0 {
+			e.emitf("%s", d.String())
+		}
+		return
+	}
+	for i := 0; i < d.Len(); i++ {
+		e.emitf("%s", d.Index(i).String())
+	}
+}
+
+func fileInfoDiff(e *emitter, t reflect.Type, a, b fs.FileInfo) {
+	e.config.helper()
+	if a.Name() != b.Name() {
+		e.subf(t, ".Name()").emitf("%q != %q", a.Name(), b.Name())
+	}
+	if a.Size() != b.Size() {
+		e.subf(t, ".Size()").emitf("%d != %d", a.Size(), b.Size())
+	}
+	if a.Mode() != b.Mode() {
+		e.subf(t, ".Mode()").emitf("%v != %v", a.Mode(), b.Mode())
+	}
+	at, bt := a.ModTime(), b.ModTime()
+	if d := bt.Sub(at); d > e.config.fileInfoTol || d < -e.config.fileInfoTol {
+		as := at.Format(e.config.timeLayout)
+		bs := bt.Format(e.config.timeLayout)
+		e.subf(t, ".ModTime()").emitf("%s != %s (%s)", as, bs, d)
+	}
+	if a.IsDir() != b.IsDir() {
+		e.subf(t, ".IsDir()").emitf("%v != %v", a.IsDir(), b.IsDir())
+	}
+}
+
+// hasGRPCStatus returns whether t has a method GRPCStatus
+// with no arguments and one result, as gRPC status errors do.
+func hasGRPCStatus(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	m, ok := t.MethodByName("GRPCStatus")
+	return ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1
+}
+
+// grpcStatusDiff compares the statuses returned by
+// the GRPCStatus methods of av and bv, by their codes,
+// messages, and details.
+func grpcStatusDiff(e *emitter, t reflect.Type, av, bv reflect.Value) {
+	e.config.helper()
+	as := av.MethodByName("GRPCStatus").Call(nil)[0]
+	bs := bv.MethodByName("GRPCStatus").Call(nil)[0]
+	ac, am, ad := statusParts(as)
+	bc, bm, bd := statusParts(bs)
+	if ac != bc {
+		e.emitf("code=%s != code=%s", ac, bc)
+	}
+	if am != bm {
+		e.emitf("message=%+q != message=%+q", am, bm)
+	}
+	walk(e.subf(t, ".GRPCStatus().Details()"), ad, bd, true, false)
+}
+
+// statusParts returns the code, message, and details
+// of gRPC status s, from its methods Code, Message,
+// and Details. Missing methods give zero values.
+func statusParts(s reflect.Value) (code, msg string, details reflect.Value) {
+	call := func(name string) reflect.Value {
+		if s.Kind() == reflect.Interface || s.Kind() == reflect.Ptr {
+			if s.IsNil() {
+				return reflect.Value{}
+			}
+		}
+		m := s.MethodByName(name)
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			return reflect.Value{}
+		}
+		return addressable(m.Call(nil)[0])
+	}
+	if c := call("Code"); c.IsValid() {
+		code = fmt.Sprint(c.Interface())
+	}
+	if m := call("Message"); m.IsValid() && m.Kind() == reflect.String {
+		msg = m.String()
+	}
+	return code, msg, call("Details")
+}
+
+// A multiError is an error that wraps several errors,
+// such as one returned by errors.Join.
+type multiError interface {
+	error
+	Unwrap() []error
+}
+
+// multiErrorDiff compares the messages of the errors
+// wrapped by a and b, in order for MultiErrorOrdered,
+// or else as a multiset.
+func multiErrorDiff(e *emitter, t reflect.Type, a, b multiError) {
+	e.config.helper()
+	as, bs := errorMessages(a), errorMessages(b)
+	if !e.config.multiErrorOrdered {
+		// Report each message present on only one side.
+		slices.Sort(as)
+		slices.Sort(bs)
+		for _, ed := range diffseq.DiffSlice(as, bs) {
+			for _, msg := range as[ed.A0:ed.A1] {
+				e.emitf("%s %q", e.config.removedLabel, msg)
+			}
+			for _, msg := range bs[ed.B0:ed.B1] {
+				e.emitf("%s %q", e.config.addedLabel, msg)
+			}
+		}
+		return
+	}
+	for _, ed := range diffseq.DiffSlice(as, bs) {
+		n := min(ed.A1-ed.A0, ed.B1-ed.B0)
+		for i := 0; i < n; i++ {
+			ee := e.subf(t, "[%d]", ed.A0+i)
+			ee.emitf("%q != %q", as[ed.A0+i], bs[ed.B0+i])
+		}
+		for i := ed.A0 + n; i < ed.A1; i++ {
+			e.subf(t, "[%d]", i).emitf("%s %q", e.config.removedLabel, as[i])
+		}
+		for i := ed.B0 + n; i < ed.B1; i++ {
+			e.subf(t, "[%d]", ed.A1).emitf("%s %q", e.config.addedLabel, bs[i])
+		}
+	}
+}
+
+// errorMessages returns the messages of the errors
+// wrapped by err.
+func errorMessages(err multiError) []string {
+	var msgs []string
+	for _, e := range err.Unwrap() {
+		if e == nil {
+			msgs = append(msgs, "<nil>")
+			continue
+		}
+		msgs = append(msgs, e.Error())
+	}
+	return msgs
+}
+
+// gobDiff compares the gob encodings of av and bv.
+// If either value can't be encoded, it notes the error
+// and returns false, so the values are compared as usual.
+func gobDiff(e *emitter, t reflect.Type, av, bv reflect.Value) bool {
+	e.config.helper()
+	e = e.subf(t, "(gob)")
+	ab, err := gobEncode(av)
+	if err != nil {
+		e.notef("encode %s: %v", e.config.aLabel, err)
+		return false
+	}
+	bb, err := gobEncode(bv)
+	if err != nil {
+		e.notef("encode %s: %v", e.config.bLabel, err)
+		return false
+	}
+	stringDiff(e, t, string(ab), string(bb))
+	return true
+}
+
+func gobEncode(v reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).EncodeValue(v)
+	return buf.Bytes(), err
+}
+
+// absentZero returns whether av and bv, maps one of which
+// is nil, are equal when absent keys are treated as holding
+// the zero value; that is, whether the other map holds
+// only zero values.
+func absentZero(av, bv reflect.Value, c *config) bool {
+	if !c.mapAbsentZero {
+		return false
+	}
+	m := av
+	if m.IsNil() {
+		m = bv
+	}
+	iter := m.MapRange()
+	for iter.Next() {
+		if !iter.Value().IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// absentZeroEntry returns whether map elements ak and bk,
+// one of which is absent, are equal when absent keys are
+// treated as holding the zero value.
+func absentZeroEntry(ak, bk reflect.Value, c *config) bool {
+	if !c.mapAbsentZero || ak.IsValid() == bk.IsValid() {
+		return false
+	}
+	if ak.IsValid() {
+		return ak.IsZero()
+	}
+	return bk.IsZero()
+}
+
+// nilEmpty returns whether av and bv are both empty
+// and c treats nil and empty as equal.
+func nilEmpty(av, bv reflect.Value, c *config) bool {
+	return c.nilEmptyEqual && av.Len() == 0 && bv.Len() == 0
+}
+
+// jsonNumberEqual returns whether f is a float64
+// and i is an integer with exactly the same value.
+func jsonNumberEqual(f, i reflect.Value) bool {
+	if !f.IsValid() || !i.IsValid() || f.Kind() != reflect.Float64 {
+		return false
+	}
+	return floatIntEqual(f.Float(), i)
+}
+
+// numericCrossEqual returns whether a and b hold numbers of
+// different predeclared integer or floating-point types that
+// represent exactly the same value.
+// Values of named types, such as time.Duration, never match.
+func numericCrossEqual(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() || a.Type() == b.Type() {
+		return false
+	}
+	if !isBasicNumber(a.Type()) || !isBasicNumber(b.Type()) {
+		return false
+	}
+	if isFloat(a.Kind()) && isFloat(b.Kind()) {
+		return a.Float() == b.Float() // float32 converts exactly
+	}
+	if isFloat(b.Kind()) {
+		a, b = b, a
+	}
+	if isFloat(a.Kind()) {
+		return floatIntEqual(a.Float(), b)
+	}
+	if isInt(a.Kind()) && isInt(b.Kind()) {
+		return a.Int() == b.Int()
+	}
+	if isInt(b.Kind()) {
+		a, b = b, a
+	}
+	if isInt(a.Kind()) && !isInt(b.Kind()) {
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint()
+	}
+	return a.Uint() == b.Uint()
+}
+
+// isBasicNumber returns whether t is a predeclared
+// integer or floating-point type.
+func isBasicNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr, reflect.Float32, reflect.Float64:
+		return t.PkgPath() == "" && t.Name() == t.Kind().String()
+	}
+	return false
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// floatIntEqual returns whether i is an integer
+// with exactly the value x.
+func floatIntEqual(x float64, i reflect.Value) bool {
+	switch i.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		n := i.Int()
+		return x == float64(n) && x >= math.MinInt64 && x < math.MaxInt64 && int64(x) == n
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := i.Uint()
+		return x == float64(n) && x >= 0 && x < math.MaxUint64 && uint64(x) == n
+	}
+	return false
+}
+
+// imageDiff compares images a and b by their bounds
+// and the colors of their pixels, and emits a summary
+// of any pixels that differ.
+func imageDiff(e *emitter, a, b image.Image) {
+	e.config.helper()
+	r := a.Bounds()
+	if r != b.Bounds() {
+		e.emitf("bounds %v != %v", r, b.Bounds())
+		return
+	}
+	var n, maxDelta int
+	var at image.Point
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			d := colorDelta(a.At(x, y), b.At(x, y))
+			if d > int(e.config.imageTol) {
+				n++
+				if d > maxDelta {
+					maxDelta = d
+					at = image.Pt(x, y)
+				}
+			}
+		}
+	}
+	if n > 0 {
+		e.emitf("%d pixels differ (max Δ=%d at %v)", n, maxDelta, at)
+	}
+}
+
+// colorDelta returns the largest difference between
+// the 8-bit channel values of colors a and b.
+func colorDelta(a, b color.Color) int {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	d := 0
+	for _, c := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		x, y := int(c[0]>>8), int(c[1]>>8)
+		if x-y > d {
+			d = x - y
+		} else if y-x > d {
+			d = y - x
+		}
+	}
+	return d
+}
+
+// protoTimeConv returns a func that converts a value of type t
+// to time.Time or time.Duration, if t is the generated Go type
+// for protobuf well-known type google.protobuf.Timestamp or
+// google.protobuf.Duration. Otherwise it returns nil.
+//
+// It identifies those types by package, name, and fields,
+// so that this package doesn't need to depend on protobuf.
+func protoTimeConv(t reflect.Type) func(reflect.Value) reflect.Value {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	sec, ok := t.FieldByName("Seconds")
+	if !ok || sec.Type.Kind() != reflect.Int64 {
+		return nil
+	}
+	nsec, ok := t.FieldByName("Nanos")
+	if !ok || nsec.Type.Kind() != reflect.Int32 {
+		return nil
+	}
+	pkg := path.Base(t.PkgPath())
+	switch {
+	case pkg == "timestamppb" && t.Name() == "Timestamp":
+		return func(v reflect.Value) reflect.Value {
+			s := v.FieldByIndex(sec.Index).Int()
+			ns := v.FieldByIndex(nsec.Index).Int()
+			return reflect.ValueOf(time.Unix(s, ns).UTC())
+		}
+	case pkg == "durationpb" && t.Name() == "Duration":
+		return func(v reflect.Value) reflect.Value {
+			s := v.FieldByIndex(sec.Index).Int()
+			ns := v.FieldByIndex(nsec.Index).Int()
+			return reflect.ValueOf(time.Duration(s)*time.Second + time.Duration(ns))
+		}
+	}
+	return nil
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface,
+		reflect.Map, reflect.Pointer, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
 func eqtest(e *emitter, av, bv reflect.Value, a, b any, wantType bool) {
 	e.config.helper()
-	if a != b {
+	if a != b && !belowNoise(a, b, e.config.noiseFloor) {
+		if e.config.numberPercent {
+			if d, ok := percentDelta(a, b); ok {
+				e.emitf("%v != %v (%s)", e.short(av, wantType), e.short(bv, wantType), d)
+				return
+			}
+		}
 		e.emitf("%v != %v",
-			formatShort(av, wantType),
-			formatShort(bv, wantType),
+			e.short(av, wantType),
+			e.short(bv, wantType),
 		)
 	}
 }
 
+// belowNoise returns whether a and b are both numbers
+// with magnitude less than floor.
+func belowNoise(a, b any, floor float64) bool {
+	if floor <= 0 {
+		return false
+	}
+	x, aok := magnitude(a)
+	y, bok := magnitude(b)
+	return aok && bok && x < floor && y < floor
+}
+
+// magnitude returns the absolute value of number v.
+// It reports false if v is not a number.
+func magnitude(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return math.Abs(float64(v)), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return math.Abs(v), true
+	case complex128:
+		return cmplx.Abs(v), true
+	}
+	return 0, false
+}
+
+// percentDelta returns the change from a to b as a percentage
+// of a, or as an absolute difference if a is zero.
+// It reports false if a and b are not numbers.
+func percentDelta(a, b any) (string, bool) {
+	var x, y float64
+	switch a := a.(type) {
+	case int64:
+		x, y = float64(a), float64(b.(int64))
+	case uint64:
+		x, y = float64(a), float64(b.(uint64))
+	case float64:
+		x, y = a, b.(float64)
+	default:
+		return "", false
+	}
+	sign := "+"
+	if y < x {
+		sign = "-"
+	}
+	if x == 0 {
+		return "Δ" + sign + strconv.FormatFloat(math.Abs(y-x), 'g', -1, 64), true
+	}
+	p := math.Round(math.Abs((y-x)/x)*1000) / 10
+	return sign + strconv.FormatFloat(p, 'f', -1, 64) + "%", true
+}
+
+// floatEqualNaN reports whether a and b are equal,
+// treating NaN as equal to NaN.
+func floatEqualNaN(a, b float64) bool {
+	return a == b || math.IsNaN(a) && math.IsNaN(b)
+}
+
+// complexEqualNaN reports whether a and b are equal,
+// treating NaN as equal to NaN in each part.
+func complexEqualNaN(a, b complex128) bool {
+	return floatEqualNaN(real(a), real(b)) && floatEqualNaN(imag(a), imag(b))
+}
+
+// complexDiff compares complex values av and bv,
+// treating them as equal if the magnitude of their
+// difference is within the configured tolerance.
+func complexDiff(e *emitter, av, bv reflect.Value, wantType bool) {
+	e.config.helper()
+	d := cmplx.Abs(av.Complex() - bv.Complex())
+	if d <= e.config.complexTol || belowNoise(av.Complex(), bv.Complex(), e.config.noiseFloor) {
+		return
+	}
+	e.emitf("%v != %v (|Δ|=%g)",
+		e.short(av, wantType),
+		e.short(bv, wantType),
+		d,
+	)
+}
+
 func emitPointers(e *emitter, av, bv reflect.Value, wantType bool) {
 	e.config.helper()
 	e.emitf("%v != %v",
-		formatShort(av, wantType),
-		formatShort(bv, wantType),
+		e.short(av, wantType),
+		e.short(bv, wantType),
 	)
 }
 
@@ -404,38 +2047,374 @@ func stringDiff(e *emitter, t reflect.Type, a, b string) {
 		return
 	}
 
+	if dec := e.config.textDecoder; dec != nil {
+		as, aok := dec([]byte(a))
+		bs, bok := dec([]byte(b))
+		if aok && bok && as != bs {
+			textDiff(e, t, as, bs)
+			return
+		}
+		e.emitf("binary: %+q != %+q", a, b)
+		return
+	}
+
+	if e.config.forceText {
+		// Invalid bytes might be the only difference,
+		// in which case the replaced text would be equal.
+		as := strings.ToValidUTF8(a, "\ufffd")
+		bs := strings.ToValidUTF8(b, "\ufffd")
+		if as != bs {
+			textDiff(e, t, as, bs)
+			return
+		}
+	}
+
+	if e.config.byteChars {
+		byteDiff(e, t, a, b)
+		return
+	}
+
 	// TODO(kr): binary diff, hex, something
 	e.emitf("binary: %+q != %+q", a, b)
 }
 
 func seqDiff(e *emitter, as, bs reflect.Value) {
 	e.config.helper()
+	if kf, ok := e.config.keyedSlice[as.Type().Elem()]; ok {
+		keyedSeqDiff(e, as, bs, kf)
+		return
+	}
+	if e.config.patch != nil {
+		patchSeqDiff(e, as, bs)
+		return
+	}
 	eq := func(a, b reflect.Value, ai, bi int) bool {
 		av := a.Index(ai)
 		bv := b.Index(bi)
 		return equal(av, bv, &e.config, true)
 	}
-	for _, ed := range diffseq.Diff(as, bs, eq) {
+	edits := diffseq.Diff(as, bs, eq)
+	if e.config.onVisit != nil {
+		visitEqual(e, as, bs, edits)
+	}
+	var moved map[int]int // index in as -> index in bs
+	var bMoved map[int]bool
+	if e.config.detectMoves {
+		moved, bMoved = findMoves(e, as, bs, edits)
+	}
+	lim := e.limiter()
+	defer lim.emitMore(e, as, bs)
+	for _, ed := range edits {
 		a0, a1 := ed.A0, ed.A1
 		b0, b1 := ed.B0, ed.B1
+		var ai, bi []int // indexes not accounted for by moves
+		for i := a0; i < a1; i++ {
+			if j, ok := moved[i]; ok {
+				if !lim.allow() {
+					continue
+				}
+				ee := e.subf(as.Type(), "")
+				ee.set(as.Index(i), bs.Index(j))
+				ee.emitf("moved [%d]->[%d] %v", i, j, e.short(as.Index(i), false))
+				continue
+			}
+			ai = append(ai, i)
+		}
+		for i := b0; i < b1; i++ {
+			if !bMoved[i] {
+				bi = append(bi, i)
+			}
+		}
+		if len(ai) < a1-a0 || len(bi) < b1-b0 {
+			ed = diffseq.Edit{} // no longer a contiguous run
+		}
+		if e.config.collapseRanges && isRangeReplace(ed) {
+			if !lim.allow() {
+				continue
+			}
+			ee := e.subf(as.Type(), "[%d:%d]", a0, a1)
+			// Slicing an array requires an addressable value.
+			aslice := addressable(as).Slice(a0, a1)
+			bslice := addressable(bs).Slice(b0, b1)
+			ee.set(aslice, bslice)
+			ee.emitf("%v != %v", e.short(aslice, false), e.short(bslice, false))
+			continue
+		}
 		// TODO(kr): Find a way to do "fuzzy myers" so we can match
 		// up the "most similar" pairs instead of just starting at
 		// index 0 on both sides.
-		n := min(a1-a0, b1-b0)
+		n := min(len(ai), len(bi))
 		for i := 0; i < n; i++ {
-			walk(e.subf(as.Type(), "[%d]", a0+i), as.Index(a0+i), bs.Index(b0+i), true, false)
+			if !lim.allow() {
+				continue
+			}
+			ee := e.index(as.Type(), ai[i])
+			if e.config.ptrSlice && ptrNilChange(ee, as.Index(ai[i]), bs.Index(bi[i])) {
+				continue
+			}
+			walk(ee, as.Index(ai[i]), bs.Index(bi[i]), true, false)
 		}
-		for i := n; i < a1-a0; i++ {
-			ee := e.subf(as.Type(), "[%d]", a0+i)
-			ee.emitf("(removed) %v", formatShort(as.Index(a0+i), false))
+		for _, i := range ai[n:] {
+			if !lim.allow() {
+				continue
+			}
+			ee := e.index(as.Type(), i)
+			ee.set(as.Index(i), reflect.Value{})
+			ee.emitf("%s %v", e.config.removedLabel, e.short(as.Index(i), false))
 		}
-		for i := n; i < b1-b0; i++ {
-			ee := e.subf(as.Type(), "[%d]", a0) // NOTE(kr): no +i
-			ee.emitf("(added) %v", formatShort(bs.Index(b0+i), false))
+		for _, i := range bi[n:] {
+			if !lim.allow() {
+				continue
+			}
+			ee := e.index(as.Type(), a0) // NOTE(kr): no +i
+			ee.set(reflect.Value{}, bs.Index(i))
+			ee.emitf("%s %v", e.config.addedLabel, e.short(bs.Index(i), false))
 		}
 	}
 }
 
+// ptrNilChange emits a message and returns true if av and bv
+// are pointers and exactly one of them is nil,
+// for PtrSliceSemantics.
+func ptrNilChange(e *emitter, av, bv reflect.Value) bool {
+	e.config.helper()
+	if av.Kind() != reflect.Ptr || av.IsNil() == bv.IsNil() {
+		return false
+	}
+	e.set(av, bv)
+	if av.IsNil() {
+		e.emitf("(nil->set) %v", e.short(bv, false))
+	} else {
+		e.emitf("(set->nil) %v", e.short(av, false))
+	}
+	return true
+}
+
+// visitEqual walks the elements of as and bs that are
+// not in edits, so OnVisit sees their leaves.
+// They are equal, so this emits nothing.
+func visitEqual(e *emitter, as, bs reflect.Value, edits []diffseq.Edit) {
+	e.config.helper()
+	i, j := 0, 0
+	for _, ed := range append(edits, diffseq.Edit{A0: as.Len(), B0: bs.Len()}) {
+		for ; i < ed.A0; i, j = i+1, j+1 {
+			walk(e.index(as.Type(), i), as.Index(i), bs.Index(j), true, false)
+		}
+		i, j = ed.A1, ed.B1
+	}
+}
+
+// limiter counts the differing elements of one container,
+// for PerContainerLimit.
+type limiter struct {
+	limit int // max elements to show in detail, or 0 for no limit
+	n     int // differing elements seen so far
+}
+
+func (e *emitter) limiter() *limiter {
+	return &limiter{limit: e.config.containerLimit}
+}
+
+// allow counts a differing element and reports
+// whether to show it in detail.
+func (l *limiter) allow() bool {
+	l.n++
+	return l.limit <= 0 || l.n <= l.limit
+}
+
+// emitMore emits the number of differing elements
+// of container av, bv not shown in detail, if any.
+func (l *limiter) emitMore(e *emitter, av, bv reflect.Value) {
+	if l.limit <= 0 || l.n <= l.limit {
+		return
+	}
+	ee := e.subf(av.Type(), "")
+	ee.set(av, bv)
+	ee.emitf("(+ %d more differing elements)", l.n-l.limit)
+}
+
+// keyedSeqDiff compares sequences as and bs by matching
+// up elements with equal keys, as computed by func kf.
+// If several elements have the same key, they are matched
+// in order of their appearance.
+// Matched elements are compared at their index in as.
+// Elements of as with no match are reported as removed,
+// followed by elements of bs with no match, reported as added.
+func keyedSeqDiff(e *emitter, as, bs reflect.Value, kf reflect.Value) {
+	e.config.helper()
+	bIndex := map[any][]int{}
+	for j := 0; j < bs.Len(); j++ {
+		k := sliceKey(kf, bs.Index(j))
+		bIndex[k] = append(bIndex[k], j)
+	}
+	matched := make([]bool, bs.Len())
+	lim := e.limiter()
+	defer lim.emitMore(e, as, bs)
+	for i := 0; i < as.Len(); i++ {
+		k := sliceKey(kf, as.Index(i))
+		if js := bIndex[k]; len(js) > 0 {
+			bIndex[k] = js[1:]
+			matched[js[0]] = true
+			if lim.limit > 0 && equal(as.Index(i), bs.Index(js[0]), &e.config, true) || !lim.allow() {
+				continue
+			}
+			walk(e.index(as.Type(), i), as.Index(i), bs.Index(js[0]), true, false)
+			continue
+		}
+		if !lim.allow() {
+			continue
+		}
+		ee := e.index(as.Type(), i)
+		ee.set(as.Index(i), reflect.Value{})
+		ee.emitf("%s %v", e.config.removedLabel, e.short(as.Index(i), false))
+	}
+	for j := 0; j < bs.Len(); j++ {
+		if !matched[j] && lim.allow() {
+			ee := e.index(as.Type(), j)
+			ee.set(reflect.Value{}, bs.Index(j))
+			ee.emitf("%s %v", e.config.addedLabel, e.short(bs.Index(j), false))
+		}
+	}
+}
+
+// sliceKey returns the key of v given by KeyedSlice func kf.
+// It panics if the key can't be used as a map key.
+func sliceKey(kf, v reflect.Value) any {
+	k := reflectApply(kf, v).Interface()
+	if t := reflect.TypeOf(k); t != nil && !t.Comparable() {
+		panic("diff: KeyedSlice key of type " + t.String() + " is not comparable")
+	}
+	return k
+}
+
+// findMoves pairs elements of as removed by edits
+// with equal elements of bs inserted by edits.
+// When there are several candidates, it picks the one
+// whose index is nearest.
+// It returns the pairing as a map from index in as
+// to index in bs, along with the set of paired indexes in bs.
+func findMoves(e *emitter, as, bs reflect.Value, edits []diffseq.Edit) (map[int]int, map[int]bool) {
+	e.config.helper()
+	moved := map[int]int{}
+	bMoved := map[int]bool{}
+	for _, aed := range edits {
+		for i := aed.A0; i < aed.A1; i++ {
+			best := -1
+			for _, bed := range edits {
+				for j := bed.B0; j < bed.B1; j++ {
+					if bMoved[j] || best >= 0 && abs(j-i) >= abs(best-i) {
+						continue
+					}
+					if equal(as.Index(i), bs.Index(j), &e.config, true) {
+						best = j
+					}
+				}
+			}
+			if best >= 0 {
+				moved[i] = best
+				bMoved[best] = true
+			}
+		}
+	}
+	return moved, bMoved
+}
+
+// mapValueSetDiff compares the values of maps av and bv
+// as multisets, ignoring their keys.
+// It emits a difference for each value in one map
+// that has no equal counterpart in the other.
+func mapValueSetDiff(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	var avals, bvals []reflect.Value
+	for _, k := range sortedKeys(av) {
+		avals = append(avals, addressable(av.MapIndex(k)))
+	}
+	for _, k := range sortedKeys(bv) {
+		bvals = append(bvals, addressable(bv.MapIndex(k)))
+	}
+	multisetDiff(e, av, bv, avals, bvals)
+}
+
+// multisetDiff compares avals and bvals, the elements
+// of containers av and bv, as multisets.
+// It emits a difference for each element in one
+// with no equal counterpart in the other.
+func multisetDiff(e *emitter, av, bv reflect.Value, avals, bvals []reflect.Value) {
+	e.config.helper()
+	t := av.Type()
+	matched := make([]bool, len(bvals))
+	var removed []reflect.Value
+	for _, a := range avals {
+		found := false
+		for j, b := range bvals {
+			if !matched[j] && equal(a, b, &e.config, true) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, a)
+		}
+	}
+	lim := e.limiter()
+	defer lim.emitMore(e, av, bv)
+	for _, a := range removed {
+		if !lim.allow() {
+			continue
+		}
+		esub := e.subf(t, "")
+		esub.set(a, reflect.Value{})
+		esub.emitf("%s %v", e.config.removedLabel, e.short(a, false))
+	}
+	for j, b := range bvals {
+		if !matched[j] && lim.allow() {
+			esub := e.subf(t, "")
+			esub.set(reflect.Value{}, b)
+			esub.emitf("%s %v", e.config.addedLabel, e.short(b, false))
+		}
+	}
+}
+
+// unorderedDiff compares sequences as and bs
+// as multisets, ignoring the order of their elements.
+func unorderedDiff(e *emitter, as, bs reflect.Value) {
+	e.config.helper()
+	var avals, bvals []reflect.Value
+	for i := 0; i < as.Len(); i++ {
+		avals = append(avals, as.Index(i))
+	}
+	for i := 0; i < bs.Len(); i++ {
+		bvals = append(bvals, bs.Index(i))
+	}
+	multisetDiff(e, as, bs, avals, bvals)
+}
+
+// fieldTag reports how struct field f should be compared,
+// according to its struct tag with the given key.
+// See RespectTags.
+func fieldTag(f reflect.StructField, key string) (ignore, unordered bool) {
+	name, opts, _ := strings.Cut(f.Tag.Get(key), ",")
+	if name == "-" || name == "ignore" {
+		return true, false
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "unordered" {
+			unordered = true
+		}
+	}
+	return false, unordered
+}
+
+// isRangeReplace returns whether ed replaces
+// more than one element with at least one element.
+func isRangeReplace(ed diffseq.Edit) bool {
+	na, nb := ed.A1-ed.A0, ed.B1-ed.B0
+	return na > 0 && nb > 0 && (na > 1 || nb > 1)
+}
+
 func sortedKeys(maps ...reflect.Value) []reflect.Value {
 	t := reflect.MapOf(maps[0].Type().Key(), reflectBool)
 	merged := reflect.MakeMap(t)
@@ -448,8 +2427,20 @@ func sortedKeys(maps ...reflect.Value) []reflect.Value {
 	return fmtsort.Sort(merged).Key
 }
 
+// orderedKeys is like sortedKeys, but if order has a less
+// func for the key type, it sorts the keys using that.
+func orderedKeys(order map[reflect.Type]reflect.Value, maps ...reflect.Value) []reflect.Value {
+	keys := sortedKeys(maps...)
+	if less, ok := order[maps[0].Type().Key()]; ok {
+		slices.SortStableFunc(keys, func(a, b reflect.Value) bool {
+			return reflectApply(less, a, b).Bool()
+		})
+	}
+	return keys
+}
+
 func addressable(r reflect.Value) reflect.Value {
-	if !r.IsValid() {
+	if !r.IsValid() || r.CanAddr() {
 		return r
 	}
 	a := reflect.New(r.Type()).Elem()
@@ -473,3 +2464,17 @@ func min[T constraints.Ordered](a, b T) T {
 	}
 	return b
 }
+
+func max[T constraints.Ordered](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
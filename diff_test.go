@@ -108,6 +108,71 @@ func TestMapSort(t *testing.T) {
 	}
 }
 
+func TestMapInterfaceKeys(t *testing.T) {
+	a := map[any]int{1: 1, "a": 2, [2]int{1, 2}: 3, NaN: 4}
+	b := map[any]int{1: 1, "a": 3, [2]int{1, 3}: 3}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b)
+	want := "map[any]int[[2]int{1, 2}]: (removed)\n" +
+		"map[any]int[[2]int{1, 3}]: (added) 3\n" +
+		"map[any]int[\"a\"]: 2 != 3\n" +
+		"map[any]int[NaN]: (uncomparable map key)\n"
+	if got != want {
+		t.Errorf("got:\n%s", got)
+		t.Errorf("want:\n%s", want)
+	}
+}
+
+func TestMapUnexportedPicky(t *testing.T) {
+	type T struct{ v time.Time }
+	type U struct{ m map[int]T }
+	t1, t2 := time.Unix(1, 0).UTC(), time.Unix(2, 0).UTC()
+	cases := []struct {
+		a, b any
+	}{
+		{map[int]T{1: {t1}}, map[int]T{1: {t2}}},
+		{[]U{{map[int]T{1: {t1}}}}, []U{{map[int]T{1: {t1}, 2: {t2}}}}},
+		{map[int]U{1: {map[int]T{1: {t1}}}}, map[int]U{2: {map[int]T{1: {t1}, 2: {t2}}}}},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.Picky)
+		if got == "" || strings.Contains(got, "PANIC") {
+			t.Errorf("Each(%v, %v, Picky) = %q, want differences", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestMapFuncValues(t *testing.T) {
+	f1, f2 := func() {}, func() {}
+	a := map[string]func(){"a": f1, "b": nil, "c": f1, "d": f1}
+	b := map[string]func(){"a": f2, "b": nil, "c": nil, "e": f2}
+	cases := []struct {
+		equalFuncs bool
+		want       string
+	}{
+		{true, "map[string]func()[\"c\"]: func() {...} != nil\n" +
+			"map[string]func()[\"d\"]: (removed)\n" +
+			"map[string]func()[\"e\"]: (added) func() {...}\n"},
+		{false, "map[string]func()[\"a\"]: func() {...} != func() {...}\n" +
+			"map[string]func()[\"c\"]: func() {...} != nil\n" +
+			"map[string]func()[\"d\"]: (removed)\n" +
+			"map[string]func()[\"e\"]: (added) func() {...}\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, diff.EqualFuncs(tt.equalFuncs))
+		if got != tt.want {
+			t.Errorf("EqualFuncs(%v):", tt.equalFuncs)
+			t.Errorf("got:\n%s", got)
+			t.Errorf("want:\n%s", tt.want)
+		}
+	}
+}
+
 func TestUnequal(t *testing.T) {
 	var cases = [][2]any{
 		{[1]int{0}, [1]int{1}},
@@ -194,6 +259,8 @@ func TestCycle(t *testing.T) {
 		b1.P = b2
 		testUnequal(t, a, b1)
 		testUnequal(t, b1, a)
+		testCycleMessage(t, a, b1, "diff_test.T.P: uneven cycle: got revisits the root, want does not")
+		testCycleMessage(t, b1, a, "diff_test.T.P: uneven cycle: want revisits the root, got does not")
 	})
 
 	t.Run("equal and uneven x3", func(t *testing.T) {
@@ -205,7 +272,34 @@ func TestCycle(t *testing.T) {
 		b1.P = b3
 		testUnequal(t, a, b1)
 		testUnequal(t, b1, a)
+		testCycleMessage(t, a, b1, "diff_test.T.P: uneven cycle: got revisits the root, want does not")
 	})
+
+	t.Run("different cycle targets", func(t *testing.T) {
+		// a: a1 -> a2 -> a2
+		// b: b1 -> b2 -> b1
+		a2 := &T{N: 1}
+		a2.P = a2
+		a1 := &T{N: 1, P: a2}
+		b1 := &T{N: 1}
+		b2 := &T{N: 1, P: b1}
+		b1.P = b2
+		testCycleMessage(t, a1, b1, "diff_test.T.P.P: uneven cycle: "+
+			"got revisits diff_test.T.P, want revisits the root")
+	})
+}
+
+func testCycleMessage(t *testing.T, a, b any, want string) {
+	t.Helper()
+	var got string
+	sink := func(format string, arg ...any) {
+		t.Helper()
+		got += strings.TrimSpace(fmt.Sprintf(format, arg...))
+	}
+	diff.Test(t, sink, a, b)
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
 }
 
 func TestPath(t *testing.T) {
@@ -243,6 +337,35 @@ func TestFullRootTest(t *testing.T) {
 	}
 }
 
+type fakeTB struct {
+	testing.TB
+	got string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, arg ...any) {
+	tb.got += "E " + fmt.Sprintf(format, arg...)
+}
+
+func (tb *fakeTB) Fatalf(format string, arg ...any) {
+	tb.got += "F " + fmt.Sprintf(format, arg...)
+}
+
+func TestTB(t *testing.T) {
+	type T struct{ A, B int }
+	tb := &fakeTB{TB: t}
+	diff.TB(tb, T{1, 2}, T{1, 2})
+	diff.TB(tb, T{1, 2}, T{3, 4})
+	diff.TBFatal(tb, T{1, 2}, T{1, 3})
+	want := "E diff_test.T.A: 1 != 3\n" +
+		"E diff_test.T.B: 2 != 4\n" +
+		"F diff_test.T.B: 2 != 3\n"
+	if tb.got != want {
+		t.Errorf("output = %q, want %q", tb.got, want)
+	}
+}
+
 func TestFullRoot(t *testing.T) {
 	type T struct{ A, BB int }
 	b := &T{A: 2, BB: 4}
@@ -263,6 +386,28 @@ func TestFullRoot(t *testing.T) {
 	}
 }
 
+func TestGoLiteral(t *testing.T) {
+	type T struct{ A, BB int }
+	type C struct{ T *T }
+	b := &T{A: 2, BB: 4}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, &C{}, &C{T: b}, diff.EmitGoLiteral)
+	want := "diff_test.C:\n" +
+		"a.T:\n" +
+		"\t(*diff_test.T)(nil)\n" +
+		"b.T:\n" +
+		"\t&diff_test.T{\n" +
+		"\t\tA:  2,\n" +
+		"\t\tBB: 4,\n" +
+		"\t}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
 func TestFullField(t *testing.T) {
 	type T struct{ A, BB int }
 	type C struct{ T *T }
@@ -285,6 +430,58 @@ func TestFullField(t *testing.T) {
 	}
 }
 
+func TestFullAddedRemoved(t *testing.T) {
+	type T struct{ A, BB int }
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{
+			map[int]T{}, map[int]T{1: {1, 2}},
+			"map[int]diff_test.T:\n" +
+				"a[1]:\n" +
+				tab + "nil\n" +
+				"b[1]:\n" +
+				tab + "diff_test.T{\n" +
+				tab + tab + "A:  1,\n" +
+				tab + tab + "BB: 2,\n" +
+				tab + "}\n",
+		},
+		{
+			[]T{{3, 4}}, []T{{3, 4}, {1, 2}},
+			"[]diff_test.T:\n" +
+				"a[1]:\n" +
+				tab + "nil\n" +
+				"b[1]:\n" +
+				tab + "diff_test.T{\n" +
+				tab + tab + "A:  1,\n" +
+				tab + tab + "BB: 2,\n" +
+				tab + "}\n",
+		},
+		{
+			[]T{{3, 4}, {1, 2}}, []T{{3, 4}},
+			"[]diff_test.T:\n" +
+				"a[1]:\n" +
+				tab + "diff_test.T{\n" +
+				tab + tab + "A:  1,\n" +
+				tab + tab + "BB: 2,\n" +
+				tab + "}\n" +
+				"b[1]:\n" +
+				tab + "nil\n",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.EmitFull)
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
+
 func TestPicky(t *testing.T) {
 	type T struct{ v struct{ n int } }
 	var a, b T
@@ -311,6 +508,65 @@ func TestLog(t *testing.T) {
 	}
 }
 
+func TestFprint(t *testing.T) {
+	type T struct{ A, B int }
+	var buf bytes.Buffer
+	n, err := diff.Fprint(&buf, T{1, 2}, T{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := "diff_test.T.A: 1 != 3\ndiff_test.T.B: 2 != 4\n"
+	if got != want {
+		t.Errorf("diff.Fprint() wrote %q, want %q", got, want)
+	}
+	if n != len(want) {
+		t.Errorf("diff.Fprint() = %d, want %d", n, len(want))
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	type T struct{ A, B, C int }
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, []T{{1, 2, 3}, {4, 5, 6}}, []T{{1, 0, 0}, {0, 0, 0}}, diff.FailFast())
+	want := "[]diff_test.T[0].B: 2 != 0\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	cases := []struct {
+		got, want any
+		diff      string
+	}{
+		{1, 2, "int(2) != int(1)\n"},
+		{map[int]int{1: 1}, map[int]int{}, "map[int]int[1]: (added) 1\n"},
+		{"a\nb", "a\nc", "--- want\n+++ got\n@@ -1,2 +1,2 @@\n a\n-c\n+b\n\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		f := func(format string, arg ...any) {
+			got += fmt.Sprintf(format, arg...)
+		}
+		diff.Test(t, f, tt.got, tt.want, diff.Reverse())
+		if got != tt.diff {
+			t.Errorf("diff = %q, want %q", got, tt.diff)
+		}
+	}
+
+	var got string
+	f := func(format string, arg ...any) {
+		got += fmt.Sprintf(format, arg...)
+	}
+	diff.Test(t, f, 1, 2, diff.Reverse(), diff.EmitFull)
+	want := "any:\nwant:\n" + tab + "int(2)\ngot:\n" + tab + "int(1)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
 func TestSliceType(t *testing.T) {
 	var got string
 	gotp := (*stringPrinter)(&got)
@@ -344,6 +600,26 @@ func TestShowOrig(t *testing.T) {
 	}
 }
 
+func TestShowOrigOnChange(t *testing.T) {
+	xf := diff.Transform(func(v int) any {
+		return v / 10
+	})
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, 11, 12, xf, diff.ShowOriginalOnChange())
+	if got != "" {
+		t.Errorf("diff = %q, want empty", got)
+	}
+
+	diff.Each(gotp.Printf, 11, 21, xf, diff.ShowOriginalOnChange())
+	want := "int(transformed): int(1) != int(2)\n" +
+		"int(original): int(11) != int(21)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
 func TestTransformUnexported(t *testing.T) {
 	type T struct{ v time.Time }
 	diff.Test(t, t.Errorf, &T{}, &T{})
@@ -417,3 +693,147 @@ func (sp *stringPrinter) Printf(format string, arg ...any) (int, error) {
 	*(*string)(sp) += s
 	return len(s), nil
 }
+
+func BenchmarkEqualSlice(b *testing.B) {
+	type T struct{ A, B int }
+	x := make([]T, 10000)
+	y := make([]T, 10000)
+	for i := 0; i < b.N; i++ {
+		diff.Each(nopPrintf, x, y)
+	}
+}
+
+func nopPrintf(string, ...any) (int, error) { return 0, nil }
+
+type benchStruct struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	Next  *benchStruct
+	Items [8]struct{ A, B int }
+}
+
+func newBenchStruct() *benchStruct {
+	return &benchStruct{
+		Name:  "x",
+		Tags:  []string{"a", "b", "c"},
+		Attrs: map[string]int{"a": 1, "b": 2},
+		Next:  &benchStruct{Name: "y"},
+	}
+}
+
+func BenchmarkEachStruct(b *testing.B) {
+	x, y := newBenchStruct(), newBenchStruct()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		diff.Each(nopPrintf, x, y)
+	}
+}
+
+func BenchmarkDifferEachStruct(b *testing.B) {
+	x, y := newBenchStruct(), newBenchStruct()
+	d := diff.New()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Each(nopPrintf, x, y)
+	}
+}
+
+func TestFullCollapseEqual(t *testing.T) {
+	type T struct {
+		A int
+		B []int
+		C map[string]int
+	}
+	a := T{1, []int{1, 2}, map[string]int{"x": 1}}
+	b := T{2, []int{1, 3}, map[string]int{"x": 1}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	eq := diff.EqualFunc(func(a, b T) bool { return a.A == b.A })
+	diff.Each(gotp.Printf, a, b, eq, diff.EmitFull, diff.FullCollapseEqual())
+	want := "a:\n" +
+		tab + "diff_test.T{\n" +
+		tab + tab + "A: 1,\n" +
+		tab + tab + "B: {\n" +
+		tab + tab + tab + "…(equal),\n" +
+		tab + tab + tab + "2,\n" +
+		tab + tab + "},\n" +
+		tab + tab + "C: …(equal),\n" +
+		tab + "}\n" +
+		"b:\n" +
+		tab + "diff_test.T{\n" +
+		tab + tab + "A: 2,\n" +
+		tab + tab + "B: {\n" +
+		tab + tab + tab + "…(equal),\n" +
+		tab + tab + tab + "3,\n" +
+		tab + tab + "},\n" +
+		tab + tab + "C: …(equal),\n" +
+		tab + "}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestFullSeqContext(t *testing.T) {
+	type T struct{ S []int }
+	a := T{[]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}
+	b := T{[]int{0, 1, 2, 3, 4, 50, 6, 7, 8, 9}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	eq := diff.EqualFunc(func(a, b T) bool { return false })
+	diff.Each(gotp.Printf, a, b, eq, diff.EmitFull, diff.FullSeqContext(1))
+	want := "a:\n" +
+		tab + "diff_test.T{S:{\n" +
+		tab + tab + "...\n" +
+		tab + tab + "4,\n" +
+		tab + tab + "5,\n" +
+		tab + tab + "6,\n" +
+		tab + tab + "...\n" +
+		tab + "}}\n" +
+		"b:\n" +
+		tab + "diff_test.T{S:{\n" +
+		tab + tab + "...\n" +
+		tab + tab + "4,\n" +
+		tab + tab + "50,\n" +
+		tab + tab + "6,\n" +
+		tab + tab + "...\n" +
+		tab + "}}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestDiffer(t *testing.T) {
+	type T struct{ A, B int }
+	d := diff.New(diff.ZeroFields[T]("B"))
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	d.Each(gotp.Printf, T{1, 2}, T{1, 3})
+	d.Each(gotp.Printf, T{1, 2}, T{4, 3})
+	want := "diff_test.T(transformed).A: 1 != 4\n"
+	if got != want {
+		t.Errorf("Each diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	f := func(format string, arg ...any) {
+		got += fmt.Sprintf(format, arg...)
+	}
+	d.Test(t, f, T{5, 2}, T{6, 3})
+	want = "diff_test.T(transformed).A: 5 != 6\n"
+	if got != want {
+		t.Errorf("Test diff = %q, want %q", got, want)
+	}
+
+	if !d.Equal(T{1, 2}, T{1, 3}) {
+		t.Errorf("Equal(T{1, 2}, T{1, 3}) = false, want true")
+	}
+	if d.Equal(T{1, 2}, T{2, 2}) {
+		t.Errorf("Equal(T{1, 2}, T{2, 2}) = true, want false")
+	}
+}
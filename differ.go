@@ -0,0 +1,74 @@
+package diff
+
+import "sync"
+
+// A Differ compares values using a fixed set of options.
+// It applies its options once, in New,
+// so it is cheaper than calling Test or Each
+// with the same options many times.
+//
+// A Differ is safe to use concurrently
+// from multiple goroutines.
+// It reuses its internal state from one comparison
+// to the next, to reduce allocation.
+type Differ struct {
+	config config
+	state  sync.Pool // of *runState
+}
+
+// New returns a Differ that compares values
+// using the default options plus opt.
+// Values in opt apply in addition to (and override) the defaults.
+func New(opt ...Option) *Differ {
+	d := new(Differ)
+	d.config.init(func() {}, nil, opt...)
+	d.state.New = func() any { return newRunState() }
+	return d
+}
+
+// each is like the each function, but reuses
+// per-run state from d's pool.
+func (d *Differ) each(a, b any, c *config) {
+	c.helper()
+	s := d.state.Get().(*runState)
+	defer func() {
+		s.reset()
+		d.state.Put(s)
+	}()
+	eachState(a, b, c, s)
+}
+
+// Each compares values a and b, calling f for each difference it finds.
+// See the Each function.
+func (d *Differ) Each(f func(format string, arg ...any) (int, error), a, b any) {
+	c := d.config
+	c.sink = func(format string, arg ...any) { f(format, arg...) }
+	c.note = c.sink
+	d.each(a, b, &c)
+}
+
+// Test compares values got and want, calling f for each difference it finds.
+// See the Test function.
+func (d *Differ) Test(h Helperer, f func(format string, arg ...any), got, want any) {
+	h.Helper()
+	c := d.config
+	c.helper = h.Helper
+	c.sink = f
+	c.note = f
+	c.initTest()
+	d.each(got, want, &c)
+}
+
+// Equal reports whether a and b are equal,
+// according to d's options.
+// It stops at the first difference it finds.
+func (d *Differ) Equal(a, b any) bool {
+	c := d.config
+	n := 0
+	c.sink = func(string, ...any) { n++ }
+	c.note = func(string, ...any) {}
+	c.summarize = false
+	c.failFast = true
+	d.each(a, b, &c)
+	return n == 0
+}
@@ -0,0 +1,34 @@
+package diff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestDifferGobEqual(t *testing.T) {
+	type T struct{ F func() }
+	d := diff.New(diff.GobEqual[T]())
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	d.Each(gotp.Printf, T{}, T{})
+	if !strings.HasPrefix(got, "diff_test.T(gob): note: encode a: ") {
+		t.Errorf("Each diff = %q, want encode note", got)
+	}
+
+	got = ""
+	f := func(format string, arg ...any) {
+		got += fmt.Sprintf(format, arg...)
+	}
+	d.Test(t, f, T{}, T{})
+	if !strings.HasPrefix(got, "diff_test.T(gob): note: encode got: ") {
+		t.Errorf("Test diff = %q, want encode note", got)
+	}
+
+	if !d.Equal(T{}, T{}) {
+		t.Errorf("Equal(T{}, T{}) = false, want true")
+	}
+}
@@ -12,6 +12,7 @@ Here are some common usage examples:
 	diff.Test(t, t.Errorf, got, want)
 	diff.Test(t, t.Fatalf, got, want)
 	diff.Test(t, t.Logf, got, want)
+	diff.TB(t, got, want)
 
 	diff.Log(a, b)
 	diff.Log(a, b, diff.Logger(log.New(...)))
@@ -1,10 +1,16 @@
 package diff
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"strings"
 	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 
 	"kr.dev/diff/internal/indent"
@@ -14,17 +20,17 @@ const tab = "\u00a0\u00a0\u00a0\u00a0" // U+00A0 NO-BREAK SPACE
 
 var reflectAny = reflect.TypeOf((*any)(nil)).Elem()
 
-func formatShort(v reflect.Value, wantType bool) fmt.Formatter {
+func formatShort(v reflect.Value, wantType bool, depth int) *formatter {
 	return &formatter{
 		root:       v,
 		wantType:   wantType,
 		full:       false,
-		allowDepth: 2,
+		allowDepth: depth,
 		seen:       map[visit]bool{},
 	}
 }
 
-func formatFull(v reflect.Value) fmt.Formatter {
+func formatFull(v reflect.Value) *formatter {
 	return &formatter{
 		root:       v,
 		wantType:   true,
@@ -34,20 +40,83 @@ func formatFull(v reflect.Value) fmt.Formatter {
 	}
 }
 
+// formatFullFocus is like formatFull, but writes v in
+// relation to other, the corresponding value on the other
+// side, as controlled by the fields collapse and seqContext.
+func formatFullFocus(v, other reflect.Value, equal func(a, b reflect.Value) bool) *formatter {
+	return &formatter{
+		root:       v,
+		other:      other,
+		equal:      equal,
+		wantType:   true,
+		full:       true,
+		allowDepth: 1e8,
+		seen:       map[visit]bool{},
+	}
+}
+
+// formatGo is like formatFull, but produces valid Go syntax
+// for values that can be written as Go literals, and typed
+// nil values with a comment for those that can't.
+func formatGo(v reflect.Value) *formatter {
+	return &formatter{
+		root:       v,
+		wantType:   true,
+		full:       true,
+		goLit:      true,
+		allowDepth: 1e8,
+		seen:       map[visit]bool{},
+	}
+}
+
 type formatter struct {
-	root       reflect.Value
-	wantType   bool
-	full       bool
-	allowDepth int
-	seen       map[visit]bool
+	root        reflect.Value
+	wantType    bool
+	full        bool
+	goLit       bool // write Go syntax
+	rawStrings  bool // write multi-line strings with backquotes
+	explicitPtr bool // always write & and the type for pointers
+	bareTypes   bool // omit package qualifiers from type names
+	hideAddrs   bool // write ... in place of memory addresses
+	timeLayout  string
+	allowDepth  int
+	seen        map[visit]bool
+
+	// numberBase is the base for writing integers,
+	// overridden for particular types by numberBases.
+	numberBase  int
+	numberBases map[reflect.Type]int
+
+	// keyOrder holds less functions for ordering map keys.
+	keyOrder map[reflect.Type]reflect.Value
+
+	// If equal is non-nil, other is the value corresponding
+	// to the one being written. If collapse is set, elements
+	// for which equal reports true are elided. If seqContext
+	// is positive, slice elements further than seqContext
+	// from any differing element are elided.
+	other      reflect.Value
+	equal      func(a, b reflect.Value) bool
+	collapse   bool
+	seqContext int
 }
 
 func (f *formatter) Format(fs fmt.State, verb rune) {
 	var w io.Writer = fs
+	var buf bytes.Buffer
+	if f.goLit {
+		w = &buf
+	}
 	if f.full {
 		w = indent.New(w, tab)
 	}
 	f.writeTo(w, f.root, f.wantType, 1)
+	if f.goLit {
+		// Go source can't be indented with U+00A0,
+		// and %q escapes it in strings, so this
+		// replaces only indentation.
+		io.WriteString(fs, strings.ReplaceAll(buf.String(), tab, "\t"))
+	}
 }
 
 func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth int) {
@@ -56,6 +125,10 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		return
 	}
 	t := v.Type()
+	o := f.other
+	if o.IsValid() && o.Type() != t {
+		o = reflect.Value{}
+	}
 
 	// Check for cycles.
 	switch t.Kind() {
@@ -65,6 +138,11 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		}
 		vis := visit{unsafe.Pointer(v.Pointer()), t}
 		if f.seen[vis] {
+			if f.goLit {
+				writeTypedNil(w, t, wantType, f.full, f.bareTypes)
+				io.WriteString(w, " /* cycle */")
+				return
+			}
 			io.WriteString(w, "...")
 			return
 		}
@@ -74,7 +152,7 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 	switch t.Kind() {
 	case reflect.Array:
 		if wantType {
-			writeType(w, t, f.full)
+			writeTypeBare(w, t, f.full, f.bareTypes)
 		}
 		if depth >= f.allowDepth && t.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -89,16 +167,32 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 					io.WriteString(ww, "...\n")
 					break
 				}
-				f.writeTo(ww, v.Index(i), false, depth+1)
+				f.writeElem(ww, v.Index(i), elemAt(o, i), false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 		} else if t.Len() == 1 {
-			f.writeTo(w, v.Index(0), false, depth+1)
+			f.writeElem(w, v.Index(0), elemAt(o, 0), false, depth+1)
 		}
 		io.WriteString(w, "}")
 	case reflect.Struct:
+		if t == reflectTime && !f.goLit {
+			if tv, ok := usable(v); ok {
+				// Show times readably, even when they are
+				// compared by their internal fields.
+				layout := f.timeLayout
+				if layout == "" {
+					layout = time.RFC3339Nano
+				}
+				s := tv.Interface().(time.Time).Format(layout)
+				if wantType {
+					s = "time.Time(" + s + ")"
+				}
+				io.WriteString(w, s)
+				break
+			}
+		}
 		if wantType {
-			writeType(w, t, f.full)
+			writeTypeBare(w, t, f.full, f.bareTypes)
 		}
 		if depth >= f.allowDepth && t.NumField() > 0 {
 			io.WriteString(w, "{...}")
@@ -116,31 +210,36 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 				}
 				io.WriteString(ww, t.Field(i).Name)
 				io.WriteString(ww, ":\t")
-				f.writeTo(ww, v.Field(i), false, depth+1)
+				f.writeElem(ww, v.Field(i), fieldAt(o, i), f.goLit && isComposite(t.Field(i).Type), depth+1)
 				io.WriteString(ww, ",\n")
 			}
 			tw.Flush()
 		} else if t.NumField() == 1 {
 			io.WriteString(w, t.Field(0).Name)
 			io.WriteString(w, ":")
-			f.writeTo(w, v.Field(0), false, depth+1)
+			f.writeElem(w, v.Field(0), fieldAt(o, 0), f.goLit && isComposite(t.Field(0).Type), depth+1)
 		}
 		io.WriteString(w, "}")
 	case reflect.Func:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType, f.full)
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
+			break
+		}
+		if f.goLit {
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
+			io.WriteString(w, " /* non-nil func */")
 			break
 		}
 		fmt.Fprintf(w, "%v {...}", t)
 	case reflect.Interface:
-		f.writeTo(w, v.Elem(), true, depth)
+		f.writeElem(w, v.Elem(), elem(o), true, depth)
 	case reflect.Map:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType, f.full)
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
 			break
 		}
 		if wantType {
-			writeType(w, t, f.full)
+			writeTypeBare(w, t, f.full, f.bareTypes)
 		}
 		if depth >= f.allowDepth && v.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -148,11 +247,12 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		}
 		io.WriteString(w, "{")
 
+		v = accessMap(v)
 		if v.Len() > 1 {
 			io.WriteString(w, "\n")
 			tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
 			ww := indent.New(tw, tab)
-			for i, mk := range sortedKeys(v) {
+			for i, mk := range orderedKeys(f.keyOrder, v) {
 				if !f.full && i >= 20 {
 					io.WriteString(ww, "...\n")
 					break
@@ -160,7 +260,7 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 				mv := v.MapIndex(mk)
 				f.writeTo(ww, mk, false, 0)
 				io.WriteString(ww, ":\t")
-				f.writeTo(ww, mv, false, depth+1)
+				f.writeElem(ww, mv, mapAt(o, mk), false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 			tw.Flush()
@@ -170,16 +270,23 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 				mv := v.MapIndex(mk)
 				f.writeTo(w, mk, false, 0)
 				io.WriteString(w, ":")
-				f.writeTo(w, mv, false, depth+1)
+				f.writeElem(w, mv, mapAt(o, mk), false, depth+1)
 			}
 		}
 
 		io.WriteString(w, "}")
 	case reflect.Ptr:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType, f.full)
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
 			break
 		}
+		if f.goLit {
+			f.writeGoPtr(w, v, wantType, depth)
+			break
+		}
+		if f.explicitPtr {
+			wantType = true
+		}
 		if wantType || t.Elem().Kind() != reflect.Struct {
 			io.WriteString(w, "&")
 		}
@@ -188,14 +295,14 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 			// so show the type to be extra explicit.
 			wantType = true
 		}
-		f.writeTo(w, v.Elem(), wantType, depth) // note: don't increment depth
+		f.writeElem(w, v.Elem(), elem(o), wantType, depth) // note: don't increment depth
 	case reflect.Slice:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType, f.full)
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
 			break
 		}
 		if wantType {
-			writeType(w, t, f.full)
+			writeTypeBare(w, t, f.full, f.bareTypes)
 		}
 		if depth >= f.allowDepth && v.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -206,61 +313,278 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		if v.Len() > 1 {
 			io.WriteString(w, "\n")
 			ww := indent.New(w, tab)
+			show := f.seqShow(v, o)
 			for i := 0; i < v.Len(); i++ {
 				if !f.full && i >= 20 {
 					io.WriteString(ww, "...\n")
 					break
 				}
-				f.writeTo(ww, v.Index(i), false, depth+1)
+				if show != nil && !show[i] {
+					if i == 0 || show[i-1] {
+						io.WriteString(ww, "...\n")
+					}
+					continue
+				}
+				f.writeElem(ww, v.Index(i), elemAt(o, i), false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 		} else if v.Len() == 1 {
-			f.writeTo(w, v.Index(0), false, depth+1)
+			f.writeElem(w, v.Index(0), elemAt(o, 0), false, depth+1)
 		}
 		io.WriteString(w, "}")
 	case reflect.Bool:
-		writeSimple(w, "%v", v, wantType && t.PkgPath() != "")
+		writeSimple(w, "%v", v, wantType && t.PkgPath() != "", f.bareTypes)
 	case reflect.Int, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64:
-		writeSimple(w, "%v", v, wantType)
+		writeSimple(w, f.intVerb(t), v, wantType, f.bareTypes)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		writeSimple(w, "%v", v, wantType)
+		writeSimple(w, f.intVerb(t), v, wantType, f.bareTypes)
 	case reflect.Float32, reflect.Float64:
-		writeSimple(w, "%v", v, wantType)
+		if f.goLit && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+			writeGoFloat(w, v)
+			break
+		}
+		writeSimple(w, "%v", v, wantType, f.bareTypes)
 	case reflect.Complex64, reflect.Complex128:
-		writeSimple(w, "%v", v, wantType)
+		writeSimple(w, "%v", v, wantType, f.bareTypes)
 	case reflect.String:
 		// TODO(kr): abbreviate
-		writeSimple(w, "%q", v, wantType && t.PkgPath() != "")
+		verb := "%q"
+		if f.rawStrings && !f.goLit && canBackquote(v.String()) {
+			verb = "`%s`"
+		}
+		writeSimple(w, verb, v, wantType && t.PkgPath() != "", f.bareTypes)
 	case reflect.Chan:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType, f.full)
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
+			break
+		}
+		if f.goLit {
+			writeTypedNil(w, t, wantType, f.full, f.bareTypes)
+			fmt.Fprintf(w, " /* %p */", unsafe.Pointer(v.Pointer()))
 			break
 		}
 		io.WriteString(w, "(")
-		writeType(w, t, f.full)
+		writeTypeBare(w, t, f.full, f.bareTypes)
 		io.WriteString(w, ")")
+		if f.hideAddrs {
+			io.WriteString(w, "(...)")
+			break
+		}
 		fmt.Fprintf(w, "(%p)", unsafe.Pointer(v.Pointer()))
 	case reflect.UnsafePointer:
+		if f.goLit {
+			fmt.Fprintf(w, "unsafe.Pointer(nil) /* %p */", unsafe.Pointer(v.Pointer()))
+			break
+		}
+		if f.hideAddrs {
+			io.WriteString(w, "unsafe.Pointer(...)")
+			break
+		}
 		fmt.Fprintf(w, "unsafe.Pointer(%p)", unsafe.Pointer(v.Pointer()))
 	default:
 		w.Write([]byte("(unknown kind)"))
 	}
 }
 
-func writeSimple(w io.Writer, verb string, v reflect.Value, showType bool) {
-	if showType {
+// writeElem writes v, an element of the value being written,
+// whose corresponding element in f.other is o.
+// If v equals o, it writes a marker instead.
+func (f *formatter) writeElem(w io.Writer, v, o reflect.Value, wantType bool, depth int) {
+	if f.equal == nil {
+		f.writeTo(w, v, wantType, depth)
+		return
+	}
+	if f.collapse && v.IsValid() && o.IsValid() && f.equal(v, o) {
+		io.WriteString(w, "…(equal)")
+		return
+	}
+	saved := f.other
+	f.other = o
+	f.writeTo(w, v, wantType, depth)
+	f.other = saved
+}
+
+// seqShow returns which elements of slice v to write
+// when f.seqContext is positive: those within seqContext
+// of an element that differs from the one at the same
+// index in o. It returns nil to write all elements,
+// including when no element differs.
+func (f *formatter) seqShow(v, o reflect.Value) []bool {
+	if f.equal == nil || f.seqContext <= 0 || !o.IsValid() {
+		return nil
+	}
+	n := f.seqContext
+	var show []bool
+	for i := 0; i < v.Len(); i++ {
+		if i < o.Len() && f.equal(v.Index(i), o.Index(i)) {
+			continue
+		}
+		if show == nil {
+			show = make([]bool, v.Len())
+		}
+		for j := max(0, i-n); j <= i+n && j < v.Len(); j++ {
+			show[j] = true
+		}
+	}
+	return show
+}
+
+// accessMap returns map v, or a copy of v that can be used
+// without restriction if v was obtained through unexported
+// fields. Unlike access, it works even if v is not
+// addressable, as is the case for a field of a map element.
+func accessMap(v reflect.Value) reflect.Value {
+	if v.CanInterface() {
+		return v
+	}
+	if v.CanAddr() {
+		return access(v)
+	}
+	p := v.UnsafePointer()
+	return reflect.NewAt(v.Type(), unsafe.Pointer(&p)).Elem()
+}
+
+// elemAt returns element i of array or slice o,
+// or the zero Value if there is no such element.
+func elemAt(o reflect.Value, i int) reflect.Value {
+	if !o.IsValid() || i >= o.Len() {
+		return reflect.Value{}
+	}
+	return o.Index(i)
+}
+
+// fieldAt returns field i of struct o,
+// or the zero Value if o is the zero Value.
+func fieldAt(o reflect.Value, i int) reflect.Value {
+	if !o.IsValid() {
+		return reflect.Value{}
+	}
+	return o.Field(i)
+}
+
+// mapAt returns the element of map o with key k,
+// or the zero Value if there is no such element.
+func mapAt(o, k reflect.Value) reflect.Value {
+	if !o.IsValid() || o.IsNil() {
+		return reflect.Value{}
+	}
+	return o.MapIndex(k)
+}
+
+// elem returns the value pointed to or contained by o,
+// or the zero Value if there is no such value.
+func elem(o reflect.Value) reflect.Value {
+	if !o.IsValid() || o.IsNil() {
+		return reflect.Value{}
+	}
+	return o.Elem()
+}
+
+// writeGoPtr writes non-nil pointer v in Go syntax.
+// Go allows &T{...} only for composite types T,
+// so pointers to other types use a function literal.
+func (f *formatter) writeGoPtr(w io.Writer, v reflect.Value, wantType bool, depth int) {
+	t := v.Type()
+	switch t.Elem().Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.Struct:
+		if !wantType {
+			// Elided &T in a composite literal element.
+			f.writeTo(w, v.Elem(), false, depth)
+			return
+		}
+		io.WriteString(w, "&")
+		f.writeTo(w, v.Elem(), true, depth)
+	default:
+		io.WriteString(w, "func() ")
+		writeTypeBare(w, t, f.full, f.bareTypes)
+		io.WriteString(w, " { var v ")
+		writeTypeBare(w, t.Elem(), f.full, f.bareTypes)
+		io.WriteString(w, " = ")
+		f.writeTo(w, v.Elem(), false, depth)
+		io.WriteString(w, "; return &v }()")
+	}
+}
+
+// isComposite returns whether t is written as a composite
+// literal, possibly with & in front.
+// In Go syntax, struct field values of such types
+// must include the type.
+func isComposite(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.Struct:
+		return true
+	case reflect.Pointer:
+		return isComposite(t.Elem())
+	}
+	return false
+}
+
+// writeGoFloat writes NaN or infinite float v in Go syntax.
+func writeGoFloat(w io.Writer, v reflect.Value) {
+	needConv := v.Type() != reflect.TypeOf(float64(0))
+	if needConv {
 		writeType(w, v.Type(), false)
 		io.WriteString(w, "(")
 	}
+	switch x := v.Float(); {
+	case math.IsNaN(x):
+		io.WriteString(w, "math.NaN()")
+	case x > 0:
+		io.WriteString(w, "math.Inf(1)")
+	default:
+		io.WriteString(w, "math.Inf(-1)")
+	}
+	if needConv {
+		io.WriteString(w, ")")
+	}
+}
+
+// intVerb returns the fmt verb for
+// writing integers of type t.
+func (f *formatter) intVerb(t reflect.Type) string {
+	base, ok := f.numberBases[t]
+	if !ok {
+		base = f.numberBase
+	}
+	switch base {
+	case 2:
+		return "%#b"
+	case 8:
+		return "%O"
+	case 16:
+		return "%#x"
+	}
+	return "%v"
+}
+
+// canBackquote returns whether s has more than one line
+// and can be written unambiguously as a raw string literal.
+func canBackquote(s string) bool {
+	if !strings.Contains(s, "\n") || !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == '`' || r != '\n' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeSimple(w io.Writer, verb string, v reflect.Value, showType, bare bool) {
+	if showType {
+		writeTypeBare(w, v.Type(), false, bare)
+		io.WriteString(w, "(")
+	}
 	fmt.Fprintf(w, verb, v)
 	if showType {
 		io.WriteString(w, ")")
 	}
 }
 
-func writeTypedNil(w io.Writer, t reflect.Type, showType, full bool) {
+func writeTypedNil(w io.Writer, t reflect.Type, showType, full, bare bool) {
 	// TODO(kr): print type name here sometimes (depending on context)
 	if showType {
 		needParens := false
@@ -271,7 +595,7 @@ func writeTypedNil(w io.Writer, t reflect.Type, showType, full bool) {
 		if needParens {
 			io.WriteString(w, "(")
 		}
-		writeType(w, t, full)
+		writeTypeBare(w, t, full, bare)
 		if needParens {
 			io.WriteString(w, ")")
 		}
@@ -284,12 +608,22 @@ func writeTypedNil(w io.Writer, t reflect.Type, showType, full bool) {
 }
 
 func writeType(w io.Writer, t reflect.Type, full bool) {
+	writeTypeBare(w, t, full, false)
+}
+
+// writeTypeBare is like writeType, but if bare is true,
+// it omits the package qualifier from named types.
+func writeTypeBare(w io.Writer, t reflect.Type, full, bare bool) {
 	if t == reflectAny {
 		io.WriteString(w, "any")
 		return
 	}
 
 	if name := t.Name(); name != "" {
+		if bare {
+			io.WriteString(w, name)
+			return
+		}
 		io.WriteString(w, t.String())
 		return
 	}
@@ -297,7 +631,7 @@ func writeType(w io.Writer, t reflect.Type, full bool) {
 	switch t.Kind() {
 	case reflect.Array:
 		fmt.Fprintf(w, "[%d]", t.Len())
-		writeType(w, t.Elem(), full)
+		writeTypeBare(w, t.Elem(), full, bare)
 	case reflect.Struct:
 		io.WriteString(w, "struct{")
 		if t.NumField() > 1 {
@@ -312,7 +646,7 @@ func writeType(w io.Writer, t reflect.Type, full bool) {
 				field := t.Field(i)
 				io.WriteString(ww, field.Name)
 				io.WriteString(ww, " ")
-				writeType(ww, field.Type, full)
+				writeTypeBare(ww, field.Type, full, bare)
 				io.WriteString(ww, "\n")
 			}
 		} else if t.NumField() == 1 {
@@ -320,13 +654,13 @@ func writeType(w io.Writer, t reflect.Type, full bool) {
 			field := t.Field(0)
 			io.WriteString(w, field.Name)
 			io.WriteString(w, " ")
-			writeType(w, field.Type, full)
+			writeTypeBare(w, field.Type, full, bare)
 			io.WriteString(w, " ")
 		}
 		io.WriteString(w, "}")
 	case reflect.Func:
 		io.WriteString(w, "func")
-		writeFunc(w, t, full)
+		writeFunc(w, t, full, bare)
 	case reflect.Interface:
 		io.WriteString(w, "interface{ ")
 		for i := 0; i < t.NumMethod(); i++ {
@@ -335,20 +669,20 @@ func writeType(w io.Writer, t reflect.Type, full bool) {
 			}
 			method := t.Method(i)
 			io.WriteString(w, method.Name)
-			writeFunc(w, method.Type, full)
+			writeFunc(w, method.Type, full, bare)
 		}
 		io.WriteString(w, " }")
 	case reflect.Map:
 		io.WriteString(w, "map[")
-		writeType(w, t.Key(), full)
+		writeTypeBare(w, t.Key(), full, bare)
 		io.WriteString(w, "]")
-		writeType(w, t.Elem(), full)
+		writeTypeBare(w, t.Elem(), full, bare)
 	case reflect.Ptr:
 		io.WriteString(w, "*")
-		writeType(w, t.Elem(), full)
+		writeTypeBare(w, t.Elem(), full, bare)
 	case reflect.Slice:
 		io.WriteString(w, "[]")
-		writeType(w, t.Elem(), full)
+		writeTypeBare(w, t.Elem(), full, bare)
 	case reflect.Chan:
 		if t.ChanDir() == reflect.RecvDir {
 			io.WriteString(w, "<-")
@@ -358,13 +692,21 @@ func writeType(w io.Writer, t reflect.Type, full bool) {
 			io.WriteString(w, "<-")
 		}
 		io.WriteString(w, " ")
-		writeType(w, t.Elem(), full)
+		writeTypeBare(w, t.Elem(), full, bare)
 	default:
 		fmt.Fprint(w, t)
 	}
 }
 
-func writeFunc(w io.Writer, f reflect.Type, full bool) {
+// bareTypeName returns the name of t as written
+// by writeTypeBare, without package qualifiers.
+func bareTypeName(t reflect.Type) string {
+	var buf bytes.Buffer
+	writeTypeBare(&buf, t, false, true)
+	return buf.String()
+}
+
+func writeFunc(w io.Writer, f reflect.Type, full, bare bool) {
 	io.WriteString(w, "(")
 	n := f.NumIn()
 	for i := 0; i < n; i++ {
@@ -373,9 +715,9 @@ func writeFunc(w io.Writer, f reflect.Type, full bool) {
 		}
 		if i == n-1 && f.IsVariadic() {
 			io.WriteString(w, "...")
-			writeType(w, f.In(i).Elem(), full)
+			writeTypeBare(w, f.In(i).Elem(), full, bare)
 		} else {
-			writeType(w, f.In(i), full)
+			writeTypeBare(w, f.In(i), full, bare)
 		}
 	}
 	io.WriteString(w, ")")
@@ -390,7 +732,7 @@ func writeFunc(w io.Writer, f reflect.Type, full bool) {
 		if i > 0 {
 			io.WriteString(w, ", ")
 		}
-		writeType(w, f.Out(i), full)
+		writeTypeBare(w, f.Out(i), full, bare)
 	}
 	if n > 1 {
 		io.WriteString(w, ")")
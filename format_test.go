@@ -3,10 +3,13 @@ package diff
 import (
 	"bytes"
 	"fmt"
+	"go/parser"
 	"io"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -73,7 +76,7 @@ func TestWriteShortSpecial(t *testing.T) {
 	for i, tt := range cases {
 		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
 			rv := reflect.ValueOf(tt.v)
-			got := fmt.Sprint(formatShort(rv, true))
+			got := fmt.Sprint(formatShort(rv, true, 2))
 			t.Logf("got: %s", got)
 			for _, want := range tt.want {
 				i := strings.Index(got, want)
@@ -519,6 +522,9 @@ func TestWriteShort(t *testing.T) {
 		{map[int]int{0: 0}, "map[int]int{0:0}"},
 		{[]int{0}, "[]int{0}"},
 
+		{time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), "time.Time(2020-01-02T03:04:05.000000006Z)"},
+		{struct{ T time.Time }{time.Unix(0, 0).UTC()}, "struct{ T time.Time }{T:1970-01-01T00:00:00Z}"},
+
 		// Trigger non-elision from each container.
 		{[1]any{0}, "[1]any{int(0)}"},
 		{struct{ V any }{0}, "struct{ V any }{V:int(0)}"},
@@ -529,7 +535,7 @@ func TestWriteShort(t *testing.T) {
 	for i, tt := range cases {
 		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
 			rv := reflect.ValueOf(tt.v)
-			got := fmt.Sprint(formatShort(rv, true))
+			got := fmt.Sprint(formatShort(rv, true, 2))
 			t.Logf("got: %s", got)
 			t.Logf("want: %s", tt.want)
 			if got != tt.want {
@@ -539,6 +545,36 @@ func TestWriteShort(t *testing.T) {
 	}
 }
 
+func TestWriteShortExplicitPointers(t *testing.T) {
+	type T struct{ V any }
+	type Empty struct{}
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{ptr(0), "&int(0)"},
+		{&T{V: 0}, "&diff.T{V:int(0)}"},
+		{[1]*int{ptr(1)}, "[1]*int{&int(1)}"},
+		{[1]**int{ptr(ptr(1))}, "[1]**int{&&int(1)}"},
+		{[1]*Empty{{}}, "[1]*diff.Empty{&diff.Empty{}}"},
+		{[]*Empty{{}}, "[]*diff.Empty{&diff.Empty{}}"},
+		{map[int]*Empty{0: {}}, "map[int]*diff.Empty{0:&diff.Empty{}}"},
+		{struct{ P *Empty }{&Empty{}}, "struct{ P *diff.Empty }{P:&diff.Empty{}}"},
+	}
+
+	for i, tt := range cases {
+		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
+			rv := reflect.ValueOf(tt.v)
+			f := formatShort(rv, true, 2)
+			f.explicitPtr = true
+			got := fmt.Sprint(f)
+			if got != tt.want {
+				t.Errorf("formatShort(%#v) = %#q, want %#q", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWriteFull(t *testing.T) {
 	type (
 		Struct0 struct{}
@@ -585,6 +621,7 @@ func TestWriteFull(t *testing.T) {
 
 		{[]int{}, tab + "[]int{}"},
 		{[]int{0}, tab + "[]int{0}"},
+		{&struct{ t time.Time }{time.Unix(0, 0).UTC()}, tab + "&struct{ t time.Time }{t:1970-01-01T00:00:00Z}"},
 		{[]int{0, 0}, tab + "[]int{\n" +
 			tab + tab + "0,\n" +
 			tab + tab + "0,\n" +
@@ -605,6 +642,68 @@ func TestWriteFull(t *testing.T) {
 	}
 }
 
+func TestWriteGo(t *testing.T) {
+	type (
+		Struct2 struct{ A, BB int }
+		Inner   struct{ N *int }
+		Outer   struct {
+			I  Inner
+			P  *Inner
+			S  []*Inner
+			M  map[string]any
+			F  func()
+			C  chan int
+			U  unsafe.Pointer
+			E  error
+			AS struct{ v, w float32 }
+		}
+		Cycle struct{ P *Cycle }
+	)
+	cyc := &Cycle{}
+	cyc.P = cyc
+	cases := []any{
+		nil,
+		1,
+		int8(-1),
+		"a\u00a0b",
+		[2]int{},
+		Struct2{0, 1},
+		&Struct2{0, 1},
+		ptr(1),
+		ptr(ptr(1)),
+		[]*int{ptr(1), nil},
+		map[Struct2][]int{{1, 2}: {3}, {4, 5}: nil},
+		[]float32{float32(math.NaN()), float32(math.Inf(1))},
+		math.Inf(-1),
+		Outer{
+			I: Inner{ptr(1)},
+			P: &Inner{},
+			S: []*Inner{{}, nil},
+			M: map[string]any{"a": 1, "b": []any{"c", 2.5, true}},
+			F: func() {},
+			C: make(chan int),
+			U: unsafe.Pointer(ptr(0)),
+		},
+		cyc,
+		(func())(nil),
+		make(chan<- int),
+	}
+
+	for i, tt := range cases {
+		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
+			rv := reflect.ValueOf(tt)
+			got := fmt.Sprintf("%#v", formatGo(rv))
+			t.Logf("got:\n%s", got)
+			if strings.ContainsRune(got, '\u00a0') {
+				t.Errorf("formatGo(%#v) contains U+00A0", tt)
+			}
+			if _, err := parser.ParseExpr(got); err != nil {
+				t.Errorf("formatGo(%#v) is not a Go expression: %v", tt, err)
+			}
+		})
+	}
+}
+
 func TestWriteCycle(t *testing.T) {
 	type T struct {
 		N int
@@ -688,3 +787,25 @@ func testWriteType[T any](t *testing.T, want string) {
 func ptr[T any](v T) *T {
 	return &v
 }
+
+func TestWriteTypeBare(t *testing.T) {
+	type T struct{}
+	testWriteTypeBare[T](t, "T")
+	testWriteTypeBare[*T](t, "*T")
+	testWriteTypeBare[[]io.Reader](t, "[]Reader")
+	testWriteTypeBare[map[string]T](t, "map[string]T")
+	testWriteTypeBare[func(T) io.Reader](t, "func(T) Reader")
+	testWriteTypeBare[struct{ R io.Reader }](t, "struct{ R Reader }")
+	testWriteTypeBare[int](t, "int")
+}
+
+func testWriteTypeBare[T any](t *testing.T, want string) {
+	t.Helper()
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	var buf bytes.Buffer
+	writeTypeBare(&buf, rt, false, true)
+	got := buf.String()
+	if got != want {
+		t.Errorf("writeTypeBare(%v) = %#q, want %#q", rt, got, want)
+	}
+}
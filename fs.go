@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"io/fs"
+	"reflect"
+
+	"golang.org/x/exp/slices"
+)
+
+// FS compares the regular files in file systems a and b,
+// calling f for each difference it finds.
+// A file present in only one file system is reported
+// as added or removed, and the contents of a file present
+// in both are compared as text, or as binary data if they
+// are not valid UTF-8, in the same way as strings.
+// Each difference is prefixed with the file's path.
+// Directories are not compared, except through the files
+// they contain.
+//
+// It returns the first error encountered reading
+// either file system, if any.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func FS(f func(format string, arg ...any) (int, error), a, b fs.FS, opt ...Option) error {
+	afiles, err := readFiles(a)
+	if err != nil {
+		return err
+	}
+	bfiles, err := readFiles(b)
+	if err != nil {
+		return err
+	}
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	var c config
+	c.init(func() {}, fdis, opt...)
+	c.noRootType = true
+	eachWalk(afiles, bfiles, &c, newRunState(), walkFiles)
+	return nil
+}
+
+// walkFiles compares av and bv, maps from file path to
+// contents as returned by readFiles.
+func walkFiles(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	afiles := av.Interface().(map[string]string)
+	bfiles := bv.Interface().(map[string]string)
+	for _, name := range sortedNames(afiles, bfiles) {
+		as, aok := afiles[name]
+		bs, bok := bfiles[name]
+		esub := e.subf(reflectString, "%s", name)
+		switch {
+		case !bok:
+			esub.set(reflect.ValueOf(as), reflect.Value{})
+			esub.emitf("%s", e.config.removedLabel)
+		case !aok:
+			esub.set(reflect.Value{}, reflect.ValueOf(bs))
+			esub.emitf("%s", e.config.addedLabel)
+		default:
+			stringDiff(esub, reflectString, as, bs)
+		}
+	}
+}
+
+// readFiles returns the contents of all regular files
+// in fsys, keyed by path.
+func readFiles(fsys fs.FS) (map[string]string, error) {
+	files := map[string]string{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, name)
+		files[name] = string(b)
+		return err
+	})
+	return files, err
+}
+
+// sortedNames returns the keys of a and b, sorted.
+func sortedNames(a, b map[string]string) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
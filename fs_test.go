@@ -0,0 +1,91 @@
+package diff_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"kr.dev/diff"
+)
+
+func TestFS(t *testing.T) {
+	a := fstest.MapFS{
+		"same.txt":     {Data: []byte("same\n")},
+		"dir/old.txt":  {Data: []byte("old\n")},
+		"dir/text.txt": {Data: []byte("a\nb\nc\n")},
+		"bin":          {Data: []byte("x\xff")},
+	}
+	b := fstest.MapFS{
+		"same.txt":     {Data: []byte("same\n")},
+		"dir/new.txt":  {Data: []byte("new\n")},
+		"dir/text.txt": {Data: []byte("a\nB\nc\n")},
+		"bin":          {Data: []byte("x\xfe")},
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	if err := diff.FS(gotp.Printf, a, b); err != nil {
+		t.Fatal(err)
+	}
+	want := `bin: binary: "x\xff" != "x\xfe"` + "\n" +
+		"dir/new.txt: (added)\n" +
+		"dir/old.txt: (removed)\n" +
+		"dir/text.txt: \n" +
+		"--- a\n" +
+		"+++ b\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		" a\n" +
+		"-b\n" +
+		"+B\n" +
+		" c\n" +
+		" \n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestFSOptions(t *testing.T) {
+	a := fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+		"c.txt": {Data: []byte("c")},
+	}
+	b := fstest.MapFS{
+		"a.txt": {Data: []byte("A")},
+		"b.txt": {Data: []byte("B")},
+	}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.FailFast(), "a.txt: \"a\" != \"A\"\n"},
+		{diff.Summarize(), "a.txt: \"a\" != \"A\"\n" +
+			"b.txt: \"b\" != \"B\"\n" +
+			"c.txt: (removed)\n" +
+			"# 3 differences\n"},
+		{diff.Reverse(), "a.txt: \"A\" != \"a\"\n" +
+			"b.txt: \"B\" != \"b\"\n" +
+			"c.txt: (added)\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		if err := diff.FS(gotp.Printf, a, b, tt.opt); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	if err := diff.FS(gotp.Printf, a, b, diff.EmitUnifiedFull); err != nil {
+		t.Fatal(err)
+	}
+	if got == "" {
+		t.Errorf("EmitUnifiedFull: no diff, want diff")
+	}
+}
@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// Golden compares got with the contents of the golden file
+// at path, reporting each difference with tb.Errorf.
+// If got is a string or a byte slice, it is compared as is;
+// otherwise it is first encoded as indented JSON.
+// Differences are reported in the same way as by Test,
+// so a multi-line golden file gets a line-by-line diff.
+//
+// With option UpdateGolden(true), Golden instead writes
+// got to the file at path, replacing its contents.
+//
+// Golden calls tb.Fatalf if it can't encode got
+// or read or write the file.
+func Golden(tb testing.TB, got any, path string, opt ...Option) {
+	tb.Helper()
+	var c config
+	c.init(tb.Helper, tb.Errorf, opt...)
+	c.initTest()
+
+	var data []byte
+	switch v := got.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		b, err := json.MarshalIndent(got, "", "\t")
+		if err != nil {
+			tb.Fatalf("golden %s: %v", path, err)
+		}
+		data = append(b, '\n')
+	}
+
+	if c.updateGolden {
+		if err := os.WriteFile(path, data, 0o666); err != nil {
+			tb.Fatalf("golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("golden: %v", err)
+	}
+	each(string(data), string(want), &c)
+}
@@ -0,0 +1,47 @@
+package diff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestGolden(t *testing.T) {
+	type T struct{ A, B int }
+	path := filepath.Join(t.TempDir(), "out.golden")
+
+	tb := &fakeTB{TB: t}
+	diff.Golden(tb, T{1, 2}, path, diff.UpdateGolden(true))
+	if tb.got != "" {
+		t.Fatalf("update output = %q, want empty", tb.got)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n\t\"A\": 1,\n\t\"B\": 2\n}\n"
+	if string(b) != want {
+		t.Errorf("golden file = %q, want %q", b, want)
+	}
+
+	diff.Golden(tb, T{1, 2}, path)
+	if tb.got != "" {
+		t.Errorf("output = %q, want empty", tb.got)
+	}
+
+	diff.Golden(tb, T{1, 3}, path)
+	want = "E --- got\n" +
+		"+++ want\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" {\n" +
+		" \t\"A\": 1,\n" +
+		"-\t\"B\": 3\n" +
+		"+\t\"B\": 2\n" +
+		" }\n" +
+		" \n\n"
+	if tb.got != want {
+		t.Errorf("output = %q, want %q", tb.got, want)
+	}
+}
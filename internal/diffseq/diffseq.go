@@ -66,20 +66,49 @@ type Equal[S Seq] func(a, b S, ai, bi int) bool
 
 // Diff finds an edit script to transform a into b.
 // Function eq is used to determine equality of items.
+//
+// Items common to the start or end of both sequences are
+// matched up front, in linear time, so equal sequences
+// (and sequences that differ only in a small region)
+// are cheap to compare.
 func Diff[S Seq](a, b S, eq Equal[S]) []Edit {
+	na, nb := a.Len(), b.Len()
+	pre := 0
+	for pre < na && pre < nb && eq(a, b, pre, pre) {
+		pre++
+	}
+	if pre == na && pre == nb {
+		return nil
+	}
+	suf := 0
+	for suf < na-pre && suf < nb-pre && eq(a, b, na-1-suf, nb-1-suf) {
+		suf++
+	}
+
 	ctx := context.Background()
-	return merge(myers.Diff(ctx, &pair[S]{a, b, eq}))
+	p := &pair[S]{a, b, eq, pre, na - pre - suf, nb - pre - suf}
+	es := merge(myers.Diff(ctx, p))
+	for i := range es {
+		es[i].A0 += pre
+		es[i].A1 += pre
+		es[i].B0 += pre
+		es[i].B1 += pre
+	}
+	return es
 }
 
+// pair is the range [off:off+na] of a and [off:off+nb] of b.
 type pair[S Seq] struct {
-	a, b S
-	eq   Equal[S]
+	a, b   S
+	eq     Equal[S]
+	off    int
+	na, nb int
 }
 
-func (p *pair[S]) LenA() int { return p.a.Len() }
-func (p *pair[S]) LenB() int { return p.b.Len() }
+func (p *pair[S]) LenA() int { return p.na }
+func (p *pair[S]) LenB() int { return p.nb }
 func (p *pair[S]) Equal(ai, bi int) bool {
-	return p.eq(p.a, p.b, ai, bi)
+	return p.eq(p.a, p.b, p.off+ai, p.off+bi)
 }
 
 // DiffSlice finds an edit script to transform a into b,
@@ -0,0 +1,55 @@
+package diffseq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSlice(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{"empty", "", "", nil},
+		{"equal", "abc", "abc", nil},
+		{"insert all", "", "ab", []Edit{{0, 0, 0, 2}}},
+		{"delete all", "ab", "", []Edit{{0, 2, 0, 0}}},
+		{"replace all", "ab", "xy", []Edit{{0, 2, 0, 2}}},
+		{"start replace", "xbcd", "ybcd", []Edit{{0, 1, 0, 1}}},
+		{"start insert", "bcd", "abcd", []Edit{{0, 0, 0, 1}}},
+		{"start delete", "abcd", "bcd", []Edit{{0, 1, 0, 0}}},
+		{"middle replace", "abxde", "abyde", []Edit{{2, 3, 2, 3}}},
+		{"middle insert", "abde", "abcde", []Edit{{2, 2, 2, 3}}},
+		{"middle delete", "abcde", "abde", []Edit{{2, 3, 2, 2}}},
+		{"end replace", "abcx", "abcy", []Edit{{3, 4, 3, 4}}},
+		{"end insert", "abc", "abcd", []Edit{{3, 3, 3, 4}}},
+		{"end delete", "abcd", "abc", []Edit{{3, 4, 3, 3}}},
+		{"start and end", "xbcy", "zbcw", []Edit{{0, 1, 0, 1}, {3, 4, 3, 4}}},
+		{"repeated", "aaa", "aaaa", []Edit{{3, 3, 3, 4}}},
+		{"overlap", "aba", "aa", []Edit{{1, 2, 1, 1}}},
+	}
+	for _, tt := range cases {
+		a, b := []byte(tt.a), []byte(tt.b)
+		got := DiffSlice(a, b)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: DiffSlice(%q, %q) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+		if s := apply(a, b, got); s != tt.b {
+			t.Errorf("%s: applying edits to %q = %q, want %q", tt.name, tt.a, s, tt.b)
+		}
+	}
+}
+
+// apply returns the result of applying es to a,
+// taking inserted items from b.
+func apply(a, b []byte, es []Edit) string {
+	var out []byte
+	i := 0
+	for _, e := range es {
+		out = append(out, a[i:e.A0]...)
+		out = append(out, b[e.B0:e.B1]...)
+		i = e.A1
+	}
+	return string(append(out, a[i:]...))
+}
@@ -0,0 +1,13 @@
+// Package durationpb is a minimal stand-in for
+// google.golang.org/protobuf/types/known/durationpb,
+// for use in tests.
+package durationpb
+
+type Duration struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	Seconds int64
+	Nanos   int32
+}
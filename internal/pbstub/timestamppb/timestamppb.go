@@ -0,0 +1,13 @@
+// Package timestamppb is a minimal stand-in for
+// google.golang.org/protobuf/types/known/timestamppb,
+// for use in tests.
+package timestamppb
+
+type Timestamp struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	Seconds int64
+	Nanos   int32
+}
@@ -0,0 +1,54 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONEach decodes JSON documents a and b and compares the
+// resulting values, calling f for each difference it finds.
+// It returns an error if either document is not valid JSON.
+//
+// Object members are compared by key, regardless of order,
+// and numbers are compared by value, so 1 and 1.0 are equal.
+// Paths are written as JSON pointers (RFC 6901),
+// such as /data/items/0/name.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func JSONEach(f func(format string, arg ...any) (int, error), a, b []byte, opt ...Option) error {
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return err
+	}
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	var c config
+	c.init(func() {}, fdis, opt...)
+	c.jsonPointer = true
+	each(av, bv, &c)
+	return nil
+}
+
+// stringJSONDiff compares strings a and b as JSON documents,
+// for StringAsJSON, if the path matches and both are valid JSON.
+// It returns false if it did not compare them.
+func stringJSONDiff(e *emitter, t reflect.Type, a, b string) bool {
+	e.config.helper()
+	pats := e.config.stringJSON
+	if len(pats) > 0 && !matchAnyPath(pats, strings.Join(e.path, "")) {
+		return false
+	}
+	var av, bv any
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+	walk(e.subf(t, "(json)"), addressable(reflect.ValueOf(&av).Elem()), addressable(reflect.ValueOf(&bv).Elem()), true, false)
+	return true
+}
@@ -0,0 +1,108 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestJSONEach(t *testing.T) {
+	a := `{"data": {"items": [{"name": "x", "n": 1}], "a/b": true, "c": 2}}`
+	b := `{"data": {"c": 2.0, "a/b": false, "items": [{"n": 1.0, "name": "y"}]}}`
+	var got string
+	gotp := (*stringPrinter)(&got)
+	err := diff.JSONEach(gotp.Printf, []byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/data/a~1b: true != false\n" +
+		`/data/items/0/name: "x" != "y"` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	for _, tt := range []struct{ path, want string }{
+		{"/data/items", `/data/items/0/name: "x" != "y"` + "\n"},
+		{"/data/*/0/name", `/data/items/0/name: "x" != "y"` + "\n"},
+		{"/data/*name", ""},
+		{"/data/a*", "/data/a~1b: true != false\n"},
+	} {
+		got = ""
+		err := diff.JSONEach(gotp.Printf, []byte(a), []byte(b), diff.OnlyPaths(tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("OnlyPaths(%q) diff = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	err = diff.JSONEach(gotp.Printf, []byte(a), []byte("{"))
+	if err == nil {
+		t.Errorf("diff.JSONEach(invalid) = nil, want error")
+	}
+}
+
+func TestJSONRaw(t *testing.T) {
+	type T struct{ Body json.RawMessage }
+	a := T{json.RawMessage(`{"name": "x", "tags": ["a", "b"], "n": 1}`)}
+	b := T{json.RawMessage(`{"n":1.0,"tags":["a","c"],"name":"x"}`)}
+	c := T{json.RawMessage(`{"tags": ["a", "b"], "n": 1, "name": "x"}`)}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, c, diff.JSONRaw())
+	diff.Each(gotp.Printf, a, b, diff.JSONRaw())
+	want := "diff_test.T.Body(transformed): \n" +
+		"--- a\n" +
+		"+++ b\n" +
+		"@@ -3,4 +3,4 @@\n" +
+		" \t\"name\": \"x\",\n" +
+		" \t\"tags\": [\n" +
+		" \t\t\"a\",\n" +
+		"-\t\t\"b\"\n" +
+		"+\t\t\"c\"\n" +
+		" \t]\n" +
+		" }\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestStringAsJSON(t *testing.T) {
+	type Event struct {
+		Name    string
+		Payload string
+	}
+	a := Event{"x", `{"id": 1, "tags": ["a", "b"]}`}
+	b := Event{"y", `{"tags":["a","c"],"id":1}`}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.StringAsJSON(), `diff_test.Event.Name: "x" != "y"` + "\n" +
+			`diff_test.Event.Payload(json)["tags"][1]: "b" != "c"` + "\n"},
+		{diff.StringAsJSON(".Payload"), `diff_test.Event.Name: "x" != "y"` + "\n" +
+			`diff_test.Event.Payload(json)["tags"][1]: "b" != "c"` + "\n"},
+		{diff.StringAsJSON(".Other"), `diff_test.Event.Name: "x" != "y"` + "\n" +
+			`diff_test.Event.Payload[2:11]: "id\": 1, \"" != ""` + "\n" +
+			`diff_test.Event.Payload[17:18]: " " != ""` + "\n" +
+			`diff_test.Event.Payload[23:24]: " " != ""` + "\n" +
+			`diff_test.Event.Payload[25:26]: "b" != "c"` + "\n" +
+			`diff_test.Event.Payload[28:28]: "" != ",\"id\":1"` + "\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, tt.opt)
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
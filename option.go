@@ -1,12 +1,19 @@
 package diff
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/exp/constraints"
 	"golang.org/x/exp/slices"
 )
 
@@ -17,6 +24,8 @@ const (
 	auto level = iota
 	pathOnly
 	full
+	goLiteral
+	unifiedFull
 )
 
 // Option values can be passed to the Each function to control
@@ -42,8 +51,11 @@ var (
 	// modifying it has no effect on the default behavior.)
 	Default Option = OptionList(
 		EmitAuto,
+		ShortDepth(2),
+		WhitespaceMarkers("\u00b7", " \u2192 "),
 		TimeEqual,
 		TimeDelta,
+		NetIP,
 		Logger(log.Default()),
 	)
 	defaultOpt = Default // actual value that cannot be changed
@@ -55,6 +67,20 @@ var (
 		TransformRemove[time.Time](),
 		FormatRemove[time.Time](),
 	)
+
+	// PickyExceptTime is like Picky, but keeps the default
+	// transform and format for time.Time, so times are
+	// compared by the instant they represent, as TimeEqual
+	// does, rather than by their internal fields.
+	// Where two times differ, it shows the original values
+	// in full as well. Other transformed types are shown
+	// as usual, without their original values.
+	PickyExceptTime Option = OptionList(
+		EmitFull,
+		Option{func(c *config) {
+			c.showOrigOnChangeType[reflectTime] = true
+		}},
+	)
 )
 
 var (
@@ -73,6 +99,39 @@ var (
 	// at that position, pretty-printed on multiple
 	// lines with indentation.
 	EmitFull Option = verbosity(full)
+
+	// EmitStable is like EmitAuto, but makes the output
+	// suitable for comparing against stored copies, such as
+	// in testscript files or golden files: whitespace is
+	// written as is, rather than with WhitespaceMarkers, and
+	// memory addresses, which vary from run to run, are
+	// written as "...".
+	// Map entries are reported in sorted key order, as in
+	// every mode, so the output does not depend on map
+	// iteration order. Keys that are pointers or channels
+	// are still sorted by address, so their order can vary.
+	// A later verbosity option, such as EmitFull, turns off
+	// the hiding of addresses, but not the whitespace setting.
+	EmitStable Option = OptionList(
+		EmitAuto,
+		WhitespaceMarkers(" ", "\t"),
+		Option{func(c *config) { c.hideAddrs = true }},
+	)
+
+	// EmitGoLiteral is like EmitFull, but writes each value
+	// as Go source that can be pasted into a program,
+	// for instance to produce golden test data.
+	// Values that have no literal form, such as non-nil
+	// channels and functions, are written as typed nil
+	// values followed by an explanatory comment.
+	EmitGoLiteral Option = verbosity(goLiteral)
+
+	// EmitUnifiedFull outputs a single unified diff of the
+	// full representations of the two values, as written by
+	// EmitFull, rather than a separate entry for each
+	// difference. It shows changes in the context of the
+	// whole structure, like a patch.
+	EmitUnifiedFull Option = verbosity(unifiedFull)
 )
 
 var (
@@ -95,17 +154,263 @@ var (
 
 	// TimeDelta outputs the difference between two times
 	// in a more readable format, including the delta between them.
-	TimeDelta Option = Format(func(a, b time.Time) string {
-		as := a.Format(time.RFC3339Nano)
-		bs := b.Format(time.RFC3339Nano)
-		return fmt.Sprintf("%s != %s (%s)", as, bs, b.Sub(a))
+	// It writes the times in the layout set by TimeFormat.
+	TimeDelta Option = Option{func(c *config) {
+		layout := &c.timeLayout // read when formatting, after all options
+		Format(func(a, b time.Time) string {
+			as := a.Format(*layout)
+			bs := b.Format(*layout)
+			return fmt.Sprintf("%s != %s (%s)", as, bs, b.Sub(a))
+		}).apply(c)
+	}}
+
+	// DurationDelta outputs the difference between two
+	// durations including the delta between them,
+	// such as "1h2m0s != 1h5m0s (Δ+3m0s)".
+	// It applies only to type time.Duration,
+	// not to other integer types.
+	DurationDelta Option = Format(func(a, b time.Duration) string {
+		sign := "+"
+		if b < a {
+			sign = ""
+		}
+		return fmt.Sprintf("%s != %s (Δ%s%s)", a, b, sign, b-a)
 	})
+
+	// NetIP outputs differences between netip.Addr,
+	// netip.Prefix, and netip.AddrPort values
+	// using their String methods,
+	// instead of showing their unexported internals.
+	NetIP Option = OptionList(
+		Format(formatStringer[netip.Addr]),
+		Format(formatStringer[netip.Prefix]),
+		Format(formatStringer[netip.AddrPort]),
+	)
+
+	// NumberPercent outputs differences between integer and
+	// floating-point values along with the relative change
+	// from the first value to the second,
+	// such as "100 != 130 (+30%)".
+	// If the first value is zero, it shows the absolute
+	// change instead, such as "0 != 5 (Δ+5)".
+	// It applies to all types of those kinds,
+	// unless they have a Format func.
+	NumberPercent Option = Option{func(c *config) {
+		c.numberPercent = true
+	}}
 )
 
+func formatStringer[T fmt.Stringer](a, b T) string {
+	return a.String() + " != " + b.String()
+}
+
 // verbosity controls how much detail is produced for each difference found.
 func verbosity(n level) Option {
 	return Option{func(c *config) {
 		c.level = n
+		c.hideAddrs = false // set again by EmitStable
+	}}
+}
+
+// ShortDepth sets how many levels of nested values are shown
+// in the short representation of values, used by EmitAuto.
+// Values nested more deeply than n are elided as {...}.
+func ShortDepth(n int) Option {
+	return Option{func(c *config) {
+		c.shortDepth = n
+	}}
+}
+
+// FailFast stops the comparison as soon as the first
+// difference is emitted, without traversing the rest
+// of the values. This saves time for large values
+// when one difference is enough.
+func FailFast() Option {
+	return Option{func(c *config) {
+		c.failFast = true
+	}}
+}
+
+// Reverse swaps the two values being compared, along with
+// their labels, so that the second value is shown first.
+// For example, Test with Reverse shows want before got,
+// reports elements present only in got as added,
+// and marks lines of got with - in text diffs.
+func Reverse() Option {
+	return Option{func(c *config) {
+		c.reverse = true
+	}}
+}
+
+// WithLabels sets the names used for the two values being
+// compared, such as "expected" and "actual". They appear in
+// the output of EmitFull and in the headers of text diffs.
+// The defaults are "a" and "b", or "got" and "want" in Test.
+func WithLabels(a, b string) Option {
+	return Option{func(c *config) {
+		c.aLabel = a
+		c.bLabel = b
+		c.customLabels = true
+	}}
+}
+
+// Labels sets the text used to mark a map entry, slice element,
+// or struct field present in only one of the values being compared.
+// Label added marks those present only in the second value (b, or want
+// in Test), and label removed marks those present only in the first
+// (a, or got in Test). The defaults are "(added)" and "(removed)".
+//
+// For example, Test with Labels("(only in want)", "(only in got)")
+// names the side each element came from.
+func Labels(added, removed string) Option {
+	return Option{func(c *config) {
+		c.addedLabel = added
+		c.removedLabel = removed
+	}}
+}
+
+// GroupByPrefix groups differences that share a common path
+// prefix, emitting the prefix once as a header line, followed
+// by each difference with the rest of its path, indented.
+// For example, instead of
+//
+//	T.Server.TLS.Cert: "a" != "b"
+//	T.Server.TLS.Key: "c" != "d"
+//
+// it emits
+//
+//	T.Server.TLS:
+//	    .Cert: "a" != "b"
+//	    .Key: "c" != "d"
+//
+// Differences are buffered until the comparison is done.
+// GroupByPrefix applies only to EmitAuto.
+func GroupByPrefix() Option {
+	return Option{func(c *config) {
+		c.groupByPrefix = true
+	}}
+}
+
+// RootTypeHook calls f once at the end of each comparison
+// with the type name that prefixes the paths of emitted
+// differences, such as "[]pkg.T". The name is computed only
+// when it is needed, so f may receive the empty string when
+// no differences are found, and always does for values that
+// have no paths inside them, such as two ints.
+func RootTypeHook(f func(string)) Option {
+	return Option{func(c *config) {
+		c.rootTypeHook = f
+	}}
+}
+
+// Summarize emits one final line giving the total number
+// of differences found, such as "# 5 differences".
+// Nothing extra is emitted if there are no differences.
+func Summarize() Option {
+	return Option{func(c *config) {
+		c.summarize = true
+	}}
+}
+
+// WhitespaceMarkers sets the strings used to show spaces
+// and tabs in multi-line text diffs, for lines whose
+// only changes are in whitespace.
+// The default markers are U+00B7 MIDDLE DOT for space
+// and U+2192 RIGHTWARDS ARROW (with padding) for tab.
+// Use WhitespaceMarkers(" ", "\t") to show whitespace as is.
+func WhitespaceMarkers(space, tab string) Option {
+	return Option{func(c *config) {
+		c.visWS = strings.NewReplacer(" ", space, "\t", tab)
+	}}
+}
+
+// ForceText diffs strings and byte slices as text even if
+// they are not valid UTF-8. Invalid bytes are shown as
+// U+FFFD REPLACEMENT CHARACTER. Values whose only
+// differences are in invalid bytes are still shown as binary.
+func ForceText() Option {
+	return Option{func(c *config) {
+		c.forceText = true
+	}}
+}
+
+// OnVisit calls f for each leaf value compared, whether
+// or not it is equal, with the path to the value as it
+// would appear in a difference, and whether it is equal.
+// Leaves are values with no parts that are compared
+// separately, such as numbers, strings, and values
+// compared by an equal func or formatted by a format func.
+// This can be used to find out which parts of a value
+// a test actually checks.
+// It has no effect on the differences reported, and
+// comparisons done internally, such as to match up
+// slice elements, are not reported.
+func OnVisit(f func(path string, equal bool)) Option {
+	return Option{func(c *config) {
+		c.onVisit = f
+	}}
+}
+
+// TimeFormat sets the layout, as for time.Time.Format,
+// used to display times, such as by TimeDelta and in the
+// representation of values containing times.
+// It affects only how times are displayed,
+// not how they are compared.
+// The default is time.RFC3339Nano.
+func TimeFormat(layout string) Option {
+	return Option{func(c *config) {
+		c.timeLayout = layout
+	}}
+}
+
+// TextLineNumbers shows line numbers in multi-line text diffs,
+// in a gutter to the left of each line.
+// Unchanged lines show their line numbers in both a and b,
+// removed lines show their line number in a,
+// and added lines show their line number in b.
+func TextLineNumbers() Option {
+	return Option{func(c *config) {
+		c.textLineNums = true
+	}}
+}
+
+// IntraLineHighlight marks the changed parts of changed
+// lines in multi-line text diffs. Where a removed line is
+// paired with an added line, the runes that differ between
+// them are enclosed in "⟦" and "⟧", as in
+//
+//	-x := ⟦foo⟧(1)
+//	+x := ⟦bar⟧(1)
+//
+// Lines that have nothing in common are left unmarked.
+func IntraLineHighlight() Option {
+	return Option{func(c *config) {
+		c.intraLine = true
+	}}
+}
+
+// AlwaysShowType shows the type of each value in the short
+// representation of values, used by EmitAuto,
+// even where the type is known from context.
+// This makes each line of output more self-contained.
+func AlwaysShowType() Option {
+	return Option{func(c *config) {
+		c.alwaysType = true
+	}}
+}
+
+// TypeForKinds shows the type of each value of the given
+// kinds in the short representation of values, used by
+// EmitAuto, like AlwaysShowType, but leaves values of other
+// kinds as usual. For example,
+//
+//	TypeForKinds(reflect.Struct, reflect.Map, reflect.Slice)
+//
+// shows the types of composite values but not of scalars.
+// Each use replaces the kinds set by any earlier one.
+func TypeForKinds(kinds ...reflect.Kind) Option {
+	return Option{func(c *config) {
+		c.typeKinds = slices.Clone(kinds)
 	}}
 }
 
@@ -118,6 +423,696 @@ func ShowOriginal() Option {
 	}}
 }
 
+// MapKeyString shows map keys in the path to a difference
+// using their String method, for key types that implement
+// fmt.Stringer. Other keys are shown in Go syntax, as usual.
+// This affects only the output, not which keys are matched.
+func MapKeyString() Option {
+	return Option{func(c *config) {
+		c.mapKeyString = true
+	}}
+}
+
+// DecimalLike compares values of any type T that has
+// both methods Equal(T) bool and String() string
+// by calling Equal, and outputs their differences
+// using String.
+// This suits types such as decimal numbers,
+// whose internal representation of a given value
+// is not unique.
+//
+// Note that this calls methods on the values being compared.
+func DecimalLike() Option {
+	return Option{func(c *config) {
+		c.decimalLike = true
+	}}
+}
+
+// PromoteEmbedded omits the names of embedded struct fields
+// from the path to a difference, when the field at the end of
+// the path is promoted. For example, a difference in field X
+// of embedded struct Inner in T is shown at T.X instead of
+// T.Inner.X.
+//
+// A field of an embedded struct is promoted when it is
+// accessible by its own name from the outermost struct,
+// following Go's selector rules. Fields that are not
+// promoted, such as those shadowed by a field of the
+// same name at a shallower depth, keep their full path.
+func PromoteEmbedded() Option {
+	return Option{func(c *config) {
+		c.promoteEmbedded = true
+	}}
+}
+
+// FileInfo compares values that implement fs.FileInfo
+// by the results of their Name, Size, Mode, ModTime,
+// and IsDir methods, and outputs differences in those terms,
+// rather than comparing their (often platform-specific)
+// internal representation.
+//
+// See also FileInfoWithin.
+func FileInfo() Option {
+	return FileInfoWithin(0)
+}
+
+// FileInfoWithin is like FileInfo, but treats modification
+// times as equal if they are within d of each other.
+func FileInfoWithin(d time.Duration) Option {
+	return Option{func(c *config) {
+		c.fileInfo = true
+		c.fileInfoTol = d
+	}}
+}
+
+// JSONNumbers treats a float64 value and an integer value
+// as equal if they represent exactly the same number,
+// but only where both are held in interface values,
+// such as the elements of a map[string]any.
+// This helps compare data decoded from JSON,
+// where all numbers are float64, with data built in Go.
+// Numbers with static types are compared as usual.
+func JSONNumbers() Option {
+	return Option{func(c *config) {
+		c.jsonNumbers = true
+	}}
+}
+
+// EqualNaNAll treats NaN as equal to NaN in values of any
+// floating-point kind, including float32 and named types,
+// and in the real and imaginary parts of complex numbers.
+// Unlike EqualNaN, which applies only to float64, it works
+// at every level, including to the elements of slices when
+// matching them up to find insertions and deletions.
+func EqualNaNAll() Option {
+	return Option{func(c *config) {
+		c.equalNaN = true
+	}}
+}
+
+// NumericCrossKind treats numbers of different predeclared
+// integer and floating-point types, such as int64(5) and
+// int(5), or int(5) and float64(5), as equal if they represent
+// exactly the same value, but only where both are held in
+// interface values, such as the elements of a map[string]any.
+// Values that differ at all, or that don't fit in the other
+// type, are still reported, along with their types.
+// Numbers of named types, such as time.Duration, and numbers
+// with static types are compared as usual.
+//
+// See also JSONNumbers.
+func NumericCrossKind() Option {
+	return Option{func(c *config) {
+		c.numericCross = true
+	}}
+}
+
+// NilEmptyEqual treats a nil map or slice as equal to
+// an empty, non-nil map or slice of the same type.
+// This applies at every level, including to the elements
+// of slices when matching them up to find insertions and
+// deletions.
+func NilEmptyEqual() Option {
+	return Option{func(c *config) {
+		c.nilEmptyEqual = true
+	}}
+}
+
+// ChannelShape compares channels by their direction,
+// capacity, and number of queued elements, rather than
+// by identity, and reports differences readably, as in
+//
+//	T.C: cap 0 != 8
+//
+// Channels whose types differ only in direction, such as
+// chan int and chan<- int held in interface values, are
+// compared this way too. A nil channel is reported as
+// different from any non-nil channel.
+// Comparison does not receive from the channels,
+// so it leaves their contents intact.
+func ChannelShape() Option {
+	return Option{func(c *config) {
+		c.chanShape = true
+	}}
+}
+
+// InterfaceTypeChanges reports values of different types,
+// such as those held in an interface field, as a change of
+// type, followed by the values themselves, as in
+//
+//	T.F: type int != string (values: 5, "x")
+//
+// rather than as a difference of values, such as
+// int(5) != "x". This helps tell type changes apart
+// from value changes when debugging changes to a schema.
+func InterfaceTypeChanges() Option {
+	return Option{func(c *config) {
+		c.typeChanges = true
+	}}
+}
+
+// PtrSliceSemantics reports elements of slices and arrays
+// of pointers that change between nil and non-nil
+// distinctly from changes to the values they point to.
+// An element that is nil only in a is reported as
+// "(nil->set)" followed by its value in b, and one that
+// is nil only in b as "(set->nil)" followed by its value in a.
+// Elements that are non-nil on both sides are compared
+// by the values they point to, as usual.
+func PtrSliceSemantics() Option {
+	return Option{func(c *config) {
+		c.ptrSlice = true
+	}}
+}
+
+// KeyedSlice compares slices and arrays with elements of
+// type T by matching up elements that have the same key,
+// as returned by key, instead of by their positions.
+// The key must be comparable with ==; Each, Test, and the
+// other comparison functions panic if key returns a value
+// whose type is not comparable.
+// Matched elements are compared to each other and any
+// differences are reported at the element's index in the
+// first value. Elements whose key is present in only one
+// value are reported as removed or added.
+// If several elements have the same key, they are
+// matched in order of their appearance.
+//
+// This gives clearer differences for lists of records
+// with stable identities, such as database rows.
+func KeyedSlice[T any](key func(T) any) Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		c.keyedSlice[t] = reflect.ValueOf(key)
+	}}
+}
+
+// PerContainerLimit shows at most k differing elements of
+// each slice, array, or map in detail, followed by a count
+// of the rest, as in
+//
+//	[]int: (+ 7 more differing elements)
+//
+// Each container has its own limit, so one large container
+// can't crowd out the differences in the others.
+// If k is 0 or less, there is no limit.
+func PerContainerLimit(k int) Option {
+	return Option{func(c *config) {
+		c.containerLimit = k
+	}}
+}
+
+// MapAbsentIsZero treats a map key that is absent on one
+// side as equal to the same key holding the zero value of
+// the map's element type on the other side, as with maps
+// of counters. Other values, such as a non-nil interface
+// holding a zero int, still count as differences.
+// This applies at every level, and a nil map is equal to
+// one holding only zero values.
+func MapAbsentIsZero() Option {
+	return Option{func(c *config) {
+		c.mapAbsentZero = true
+	}}
+}
+
+// StructByFieldName compares two struct values of different
+// types field by field, matching fields by name, instead of
+// reporting only that their types differ.
+// A field present in one struct but not the other is
+// reported as removed or added. Fields with the same name
+// but different types are reported as unequal, as usual.
+// Promoted fields of embedded structs are not matched
+// by name; only the embedded fields themselves are.
+func StructByFieldName() Option {
+	return Option{func(c *config) {
+		c.structByName = true
+	}}
+}
+
+// ComplexTolerance treats two complex numbers a and b as equal
+// if the magnitude of their difference, |a-b|, is at most tol.
+// Differences include the magnitude of the error.
+// A tolerance of zero means exact comparison, the default.
+func ComplexTolerance(tol float64) Option {
+	return Option{func(c *config) {
+		c.complexTol = tol
+	}}
+}
+
+// NoiseFloor treats two numbers as equal if the magnitude
+// of each is less than threshold, regardless of how they
+// differ from each other, as though both were zero.
+// It applies to integers, floating-point, and complex numbers.
+// Unlike ComplexTolerance, which bounds the difference
+// between two numbers, it ignores only small values;
+// a difference is reported if either number is at least
+// threshold, unless ComplexTolerance also permits it.
+// A threshold of zero disables it, the default.
+func NoiseFloor(threshold float64) Option {
+	return Option{func(c *config) {
+		c.noiseFloor = threshold
+	}}
+}
+
+// GRPCStatus compares gRPC status errors by the code,
+// message, and details of their status, rather than by
+// their internal representation, and reports differing
+// codes readably, as in
+//
+//	code=NotFound != code=Internal
+//
+// It detects such errors by their method GRPCStatus,
+// so it works without a dependency on the gRPC packages.
+// The status returned by GRPCStatus is expected to have
+// methods Code, Message, and Details, like *status.Status.
+func GRPCStatus() Option {
+	return Option{func(c *config) {
+		c.grpcStatus = true
+	}}
+}
+
+// MultiError compares errors that wrap several errors,
+// such as those returned by errors.Join, by the messages
+// of the errors they wrap, rather than by their internal
+// representation. It detects such errors by their method
+// Unwrap() []error. The messages are compared as a set,
+// ignoring order, and each message present on only one
+// side is reported as removed or added.
+//
+// See also MultiErrorOrdered.
+func MultiError() Option {
+	return Option{func(c *config) {
+		c.multiError = true
+		c.multiErrorOrdered = false
+	}}
+}
+
+// MultiErrorOrdered is like MultiError, but compares the
+// messages as a sequence, so a change in the order in which
+// errors were joined is reported as a difference.
+func MultiErrorOrdered() Option {
+	return Option{func(c *config) {
+		c.multiError = true
+		c.multiErrorOrdered = true
+	}}
+}
+
+// Image compares values that implement image.Image by their
+// bounds and the colors of their pixels, rather than by their
+// internal representation. Two pixels are treated as equal if
+// each of their 8-bit color channels (including alpha)
+// differ by at most tol. Any differences are summarized with
+// the number of differing pixels and the location of the
+// largest difference.
+func Image(tol uint8) Option {
+	return Option{func(c *config) {
+		c.image = true
+		c.imageTol = tol
+	}}
+}
+
+// CollapseRanges emits a single difference for each run of
+// consecutive slice or array elements replaced by other
+// elements, showing the ranges of elements on both sides,
+// instead of one difference for each element.
+// Runs of only inserted or only removed elements,
+// and single replaced elements, are emitted as usual.
+func CollapseRanges() Option {
+	return Option{func(c *config) {
+		c.collapseRanges = true
+	}}
+}
+
+// DetectMoves reports an element of a slice or array that was
+// removed from one position and inserted at another as a single
+// difference, such as "moved [2]->[7]", rather than as a removal
+// and a separate addition. Elements are matched using the same
+// equality as the rest of the comparison. If a removed element
+// equals more than one added element, it is matched with the
+// one whose index is nearest.
+func DetectMoves() Option {
+	return Option{func(c *config) {
+		c.detectMoves = true
+	}}
+}
+
+// ProtoTime compares values of the protobuf well-known types
+// google.protobuf.Timestamp and google.protobuf.Duration
+// (Go types timestamppb.Timestamp and durationpb.Duration)
+// as time.Time and time.Duration values, respectively,
+// instead of by their Seconds and Nanos fields.
+// Differences are then formatted by any options that
+// apply to those time types, such as TimeDelta and DurationDelta.
+func ProtoTime() Option {
+	return Option{func(c *config) {
+		c.protoTime = true
+	}}
+}
+
+// MapValuesAsSet compares maps by their values alone,
+// ignoring keys. The values of each map are treated as a
+// multiset, and a difference is emitted for each value
+// present in one map with no equal value in the other.
+// This is useful when keys are opaque identifiers
+// that are not meaningful to compare.
+func MapValuesAsSet() Option {
+	return Option{func(c *config) {
+		c.mapValuesAsSet = true
+	}}
+}
+
+// StringAsJSON compares strings that hold JSON documents,
+// such as a JSON payload embedded in a string field,
+// by the values they encode rather than as text.
+// Differences are reported below the string's path, with
+// "(json)" appended, such as `.Payload(json)["id"]`.
+// If either string is not valid JSON, they are compared
+// as usual.
+//
+// It applies to strings at one of the given paths or below
+// it, written as in OnlyPaths, or to all strings if no paths
+// are given.
+func StringAsJSON(paths ...string) Option {
+	return Option{func(c *config) {
+		c.stringJSON = append([]string{}, paths...)
+	}}
+}
+
+// OnlyPaths suppresses all differences except those found at
+// one of the given paths or below it. A path is written the
+// same way as in emitted differences, without the leading type,
+// such as ".Spec.Containers[0].Image" or `.Labels["app"]`.
+// In a path, * matches any part of a single path element,
+// so ".Items[*].Name" matches the Name field of every element.
+// For JSONEach, paths are JSON pointers, such as "/items/*/name".
+// Differences at a path enclosing one of the given paths,
+// such as a nil pointer on the way to it, are suppressed too.
+//
+// Values are still compared in full, so OnlyPaths does not
+// speed up the comparison.
+func OnlyPaths(paths ...string) Option {
+	return Option{func(c *config) {
+		c.onlyPaths = append([]string{}, paths...)
+	}}
+}
+
+// UseDiffMethod lets types describe their own differences.
+// If a type T has a method Diff(T) []string or Diff(T) string,
+// values of type T are compared by calling a.Diff(b),
+// and each string it returns is emitted as a difference.
+// If T also has a method Equal(T) bool, Diff is called
+// only when Equal reports false.
+// Otherwise, values are equal when Diff returns
+// no strings or an empty string.
+//
+// Funcs registered with EqualFunc, Transform, and Format
+// for type T take precedence over its Diff method.
+func UseDiffMethod() Option {
+	return Option{func(c *config) {
+		c.diffMethod = true
+	}}
+}
+
+// FullCollapseEqual makes EmitFull output more compact
+// by writing "…(equal)" in place of each element,
+// field, or map entry that is equal in both values,
+// leaving only the parts that differ written out in full.
+// It has no effect on other output levels.
+func FullCollapseEqual() Option {
+	return Option{func(c *config) {
+		c.fullCollapse = true
+	}}
+}
+
+// TimeInstantWithZone compares time.Time values by the instant
+// they represent, like TimeEqual, but if two equal instants
+// have locations with different names, it emits a note such
+// as "(zones differ: UTC vs Local)".
+// Times that are not equal are compared as usual.
+func TimeInstantWithZone() Option {
+	return Option{func(c *config) {
+		c.timeZone = true
+	}}
+}
+
+// RawStrings shows string values that span multiple lines
+// as raw string literals in backquotes, rather than as
+// double-quoted strings full of \n escapes.
+// Strings containing backquotes or other control
+// characters are still shown double-quoted.
+// It affects only how values are displayed, not how they
+// are compared, and it does not apply to EmitGoLiteral.
+func RawStrings() Option {
+	return Option{func(c *config) {
+		c.rawStrings = true
+	}}
+}
+
+// RespectTags lets struct types declare how their fields
+// are compared, using struct tags with the given key,
+// or "diff" if key is empty.
+// A field tagged "-" or "ignore", as in
+//
+//	CreatedAt time.Time `diff:"ignore"`
+//
+// is skipped entirely.
+// A slice or array field tagged ",unordered", as in
+//
+//	Tags []string `diff:",unordered"`
+//
+// is compared as a multiset, ignoring the order of
+// its elements, and elements present on only one side
+// are reported as removed or added.
+func RespectTags(key string) Option {
+	if key == "" {
+		key = "diff"
+	}
+	return Option{func(c *config) {
+		c.tagKey = key
+	}}
+}
+
+// NumberBase shows integer values in the given base,
+// which must be 2, 8, 10, or 16, with a prefix such as 0x
+// to indicate the base. For example, with base 16,
+// a difference is shown as "0x10 != 0x30".
+// It affects only how values are displayed,
+// not how they are compared.
+// See NumberBaseFor to set the base for a single type.
+func NumberBase(base int) Option {
+	checkBase(base)
+	return Option{func(c *config) {
+		c.numberBase = base
+	}}
+}
+
+// NumberBaseFor is like NumberBase, but applies only
+// to values of type T, such as a named type for bit flags.
+// It takes precedence over NumberBase.
+func NumberBaseFor[T constraints.Integer](base int) Option {
+	checkBase(base)
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		c.numberBases[t] = base
+	}}
+}
+
+func checkBase(base int) {
+	switch base {
+	case 2, 8, 10, 16:
+	default:
+		panic(fmt.Sprintf("diff: unsupported number base %d", base))
+	}
+}
+
+// SlicePrefix treats a slice in the first value as equal to
+// the corresponding slice in the second if it begins with
+// the same elements, ignoring any extra elements at its end.
+// In Test, this checks that got begins with want.
+// A difference is still reported if want is longer than got,
+// or if any of the leading elements differ.
+// It applies to all slices, including nested ones.
+func SlicePrefix() Option {
+	return Option{func(c *config) {
+		c.slicePrefix = true
+	}}
+}
+
+// ByteChars compares binary data, strings and byte slices
+// that are not valid UTF-8, byte by byte. Each single
+// differing byte is shown in hex and, if it is printable
+// ASCII, as a character, as in
+//
+//	[3]: 0x41 'A' != 0x42 'B'
+//
+// Longer runs of differing bytes are shown as quoted strings.
+func ByteChars() Option {
+	return Option{func(c *config) {
+		c.byteChars = true
+	}}
+}
+
+// TextDecoder sets a function to decode strings and byte
+// slices that are not valid UTF-8 to text before comparing
+// them. By default, values that are valid UTF-8 are compared
+// as text, line by line, and others as binary data. With
+// TextDecoder, if either value is not valid UTF-8, both are
+// passed to dec. Values that dec reports it can decode, such
+// as text in Latin-1, are compared as text after decoding,
+// and values it reports it can't decode are compared as
+// binary data. Pairs of valid UTF-8 values are compared as
+// text without calling dec.
+func TextDecoder(dec func([]byte) (string, bool)) Option {
+	return Option{func(c *config) {
+		c.textDecoder = dec
+	}}
+}
+
+// Base64Text compares byte slices holding base64 or PEM data
+// by their decoded contents, as a line-by-line text diff.
+// The contents of PEM blocks are shown as a hex dump,
+// as is decoded base64 data that is not UTF-8 text.
+// Byte slices that are not valid base64 or PEM are
+// shown as a hex dump of their raw bytes.
+// Data that differs only in line wrapping or padding
+// compares equal.
+func Base64Text() Option {
+	return Option{func(c *config) {
+		c.base64Text = true
+	}}
+}
+
+// ContextualStrings shows a difference between two short
+// strings as the differing middle part of each, between
+// the common prefix and suffix, like
+//
+//	…cdefgh["X" != "Y"]ijklmn…
+//
+// Only a few runes of the common prefix and suffix are shown.
+// Longer strings are compared as usual.
+func ContextualStrings() Option {
+	return Option{func(c *config) {
+		c.contextual = true
+	}}
+}
+
+// ShortTypeNames omits package qualifiers from the names
+// of types in the output, writing T instead of pkg.T.
+// Where two differing types would have the same name,
+// such as v1.T and v2.T, they are written in full.
+func ShortTypeNames() Option {
+	return Option{func(c *config) {
+		c.shortTypeNames = true
+	}}
+}
+
+// Oneof treats interface values as sum types, such as
+// protobuf oneof fields, whose dynamic type is the active
+// case. Where the two interface values hold values of
+// different types, it reports just the case on each side,
+// for example
+//
+//	a has case *pb.Msg_Name, b has case *pb.Msg_ID
+//
+// rather than the two values in full.
+func Oneof() Option {
+	return Option{func(c *config) {
+		c.oneof = true
+	}}
+}
+
+// SeqLimit sets the maximum number of values Seq and Seq2
+// collect from each iterator before comparing them.
+// Any further values are not compared.
+// The default is 10000.
+func SeqLimit(n int) Option {
+	return Option{func(c *config) {
+		c.seqLimit = n
+	}}
+}
+
+// RequireNoSharing reports a difference, "(shared pointer)",
+// wherever a pointer, map, or non-empty slice in the second
+// value refers to the same memory as one in the first,
+// instead of comparing the values they refer to.
+// This is useful for checking that a copy is a deep copy,
+// sharing no memory with the original.
+func RequireNoSharing() Option {
+	return Option{func(c *config) {
+		c.noSharing = true
+	}}
+}
+
+// InlineSegmentLimit sets the maximum number of changed
+// segments shown for a single-line string difference.
+// Strings that differ in more than n separate places,
+// by word or by rune, are shown whole, as "a != b",
+// instead of as one difference per changed segment.
+// If n is zero or less, there is no limit, which is the default.
+func InlineSegmentLimit(n int) Option {
+	return Option{func(c *config) {
+		c.inlineLimit = n
+	}}
+}
+
+// ExplicitPointers always shows pointer values with a leading &
+// and the type of the value pointed to, such as &pkg.T{...},
+// in the short representation of values used by EmitAuto.
+// By default, these are omitted where the context makes them
+// clear, such as for pointers to structs inside a slice.
+// It affects only how values are displayed.
+func ExplicitPointers() Option {
+	return Option{func(c *config) {
+		c.explicitPtr = true
+	}}
+}
+
+// CompareStructTags compares values of two struct types that
+// differ only in their field tags, instead of reporting
+// a type mismatch. It emits a difference for each field
+// whose tags differ, and compares the field values as usual.
+// The two types must have the same name, or both be unnamed,
+// and the same field names and types in the same order.
+func CompareStructTags() Option {
+	return Option{func(c *config) {
+		c.structTags = true
+	}}
+}
+
+// FullSeqContext makes EmitFull output more compact for
+// slices by writing only the elements that differ from the
+// element at the same index in the other slice, along with
+// up to n elements on either side of each. Each run of
+// other elements is written as "...".
+// If n is zero, all elements are written, as usual.
+// It has no effect on other output levels.
+func FullSeqContext(n int) Option {
+	return Option{func(c *config) {
+		c.fullSeqContext = n
+	}}
+}
+
+// NoPointerShortcut disables a fast path for pointers,
+// maps, and slices. By default, two such values that point
+// to the same location are treated as equal without
+// examining their contents. With NoPointerShortcut,
+// their contents are compared element by element anyway.
+func NoPointerShortcut() Option {
+	return Option{func(c *config) {
+		c.noPtrShortcut = true
+	}}
+}
+
+// ShowOriginalOnChange is like ShowOriginal, but shows diffs
+// of untransformed values only where the transformed values
+// differ, and omits them where the untransformed values
+// are equal.
+func ShowOriginalOnChange() Option {
+	return Option{func(c *config) {
+		c.showOrigOnChange = true
+	}}
+}
+
 // EqualFuncs controls how function values are compared.
 // If true, any two non-nil function values of the same type
 // are treated as equal;
@@ -130,6 +1125,143 @@ func EqualFuncs(b bool) Option {
 	}}
 }
 
+// UpdateGolden controls whether Golden writes its golden file.
+// If true, Golden replaces the contents of the file with the
+// value it is given, instead of comparing them.
+// This is typically set from a test flag, such as
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	diff.Golden(t, got, "testdata/out.golden", diff.UpdateGolden(*update))
+func UpdateGolden(b bool) Option {
+	return Option{func(c *config) {
+		c.updateGolden = b
+	}}
+}
+
+// IgnoreFuncs treats all function values as equal,
+// including nil and non-nil functions, and skips struct
+// fields of function type entirely, so no difference
+// is ever reported for a function.
+// This is useful for structs that hold callbacks
+// or handlers. See also EqualFuncs.
+func IgnoreFuncs() Option {
+	return Option{func(c *config) {
+		c.ignoreFuncs = true
+	}}
+}
+
+// URLCanonical compares url.URL values by a canonical form
+// of their string encodings. It sorts the query parameters in
+// RawQuery by key, and normalizes percent-encoding in the
+// escaped path, fragment, and elsewhere: hex digits are made
+// upper case and escaped unreserved characters are decoded.
+// So two URLs that differ only in query parameter order or in
+// such encoding choices are treated as equal, but an escaped
+// reserved character such as %2F remains distinct from its
+// decoded form.
+// A RawQuery that cannot be parsed is compared as is.
+// Differing URLs are shown as their original strings.
+func URLCanonical() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf(url.URL{})
+		c.equalFunc[t] = reflect.ValueOf(func(a, b url.URL) bool {
+			return canonicalURL(a) == canonicalURL(b)
+		})
+		c.format[t] = reflect.ValueOf(func(a, b url.URL) string {
+			return fmt.Sprintf("%q != %q", a.String(), b.String())
+		})
+	}}
+}
+
+func canonicalURL(u url.URL) string {
+	if q, err := url.ParseQuery(u.RawQuery); err == nil {
+		u.RawQuery = q.Encode()
+	}
+	return normalizeEscapes(u.String())
+}
+
+// normalizeEscapes rewrites the percent-encoded octets in s
+// as in RFC 3986 section 6.2.2: unreserved characters are
+// decoded and the rest use upper case hex digits.
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		x, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			b.WriteByte(s[i])
+			continue
+		}
+		if c := byte(x); isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// Regexp compares *regexp.Regexp values by their source
+// patterns, as returned by their String methods, instead of
+// by their compiled internals.
+// Differing patterns are shown as a string diff.
+func Regexp() Option {
+	return Transform(func(r *regexp.Regexp) any {
+		if r == nil {
+			return nil
+		}
+		return r.String()
+	})
+}
+
+// JSONRaw compares json.RawMessage values by the JSON values
+// they encode, rather than byte by byte, so differences in
+// whitespace and the order of object members are ignored.
+// Differing values are shown as a line-by-line diff of their
+// JSON encodings, indented and with object members sorted.
+// Values that are not valid JSON are compared as strings.
+// It applies only to type json.RawMessage, not to other
+// byte slices.
+func JSONRaw() Option {
+	return Transform(func(m json.RawMessage) any {
+		var v any
+		if err := json.Unmarshal(m, &v); err != nil {
+			return string(m)
+		}
+		b, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return string(m)
+		}
+		return string(b)
+	})
+}
+
+// OrderedMapLike transforms values of type T, a map-like
+// container that keeps its entries in order, to the list of
+// key-value pairs returned by entries. The lists are compared
+// as sequences, so entries that are added, removed, or moved
+// to a different position are reported, as well as entries
+// whose values differ.
+// See Transform for more info about transforms.
+func OrderedMapLike[T any](entries func(T) [][2]any) Option {
+	return Transform(func(v T) any {
+		return entries(v)
+	})
+}
+
 // ZeroFields transforms values of struct type T. It makes a copy of its input
 // and sets the named fields to their zero values.
 //
@@ -150,6 +1282,37 @@ func ZeroFields[T any](name ...string) Option {
 	})
 }
 
+// Defaults transforms values of type T. It makes a copy of its input
+// and sets each field that holds its zero value to the value
+// of the same field in defaults.
+// If T is not a struct type, a zero input is replaced by defaults.
+//
+// This effectively makes comparison treat unset fields as
+// holding their defaults, as for parsed configuration.
+// Fields of nested struct types are replaced only if they
+// are entirely zero; use Defaults for the nested type as well
+// to fill in their fields individually.
+// See Transform for more info about transforms.
+// See also ZeroFields.
+func Defaults[T any](defaults T) Option {
+	return Transform(func(v T) any {
+		e := reflect.ValueOf(&v).Elem()
+		d := reflect.ValueOf(&defaults).Elem()
+		if e.Kind() != reflect.Struct {
+			if e.IsZero() {
+				return defaults
+			}
+			return v
+		}
+		for i := 0; i < e.NumField(); i++ {
+			if fv := access(e.Field(i)); fv.IsZero() {
+				fv.Set(access(d.Field(i)))
+			}
+		}
+		return v
+	})
+}
+
 // KeepFields transforms values of struct type T. It makes a copy of its input,
 // preserving the named field values and setting all other fields to their
 // zero values.
@@ -238,6 +1401,122 @@ func TransformRemove[T any]() Option {
 	}}
 }
 
+// EqualFunc compares values of type T using f.
+//
+// When the values on both sides of the comparison are of
+// type T, they are passed to f. If f reports true, they are
+// treated as equal and not compared any further; otherwise
+// a difference is emitted for the two values as a whole.
+// Unlike Transform, the values are not walked again.
+//
+// See EqualFuncRemove to remove an equal func.
+func EqualFunc[T any](f func(a, b T) bool) Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		c.equalFunc[t] = reflect.ValueOf(f)
+	}}
+}
+
+// AccessorEqual compares values of type T by the values
+// returned by get, such as the result of a Load or Value
+// method on a wrapper type, instead of by their contents.
+//
+// When the values on both sides of the comparison are of
+// type T, they are passed to get, and the results are
+// compared in their place, at the same path.
+// Unlike Transform, no "(transformed)" element is added to
+// the path. If get returns a T, it is compared by its
+// contents, without calling get again.
+//
+// See AccessorEqualRemove to remove an accessor.
+func AccessorEqual[T any](get func(T) any) Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		c.accessor[t] = reflect.ValueOf(get)
+	}}
+}
+
+// AccessorEqualRemove removes any accessor for type T.
+// See AccessorEqual.
+func AccessorEqualRemove[T any]() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		delete(c.accessor, t)
+	}}
+}
+
+// MapKeyOrder sets the order in which the entries of maps
+// with keys of type K are compared and written, using less
+// to sort the keys. By default, keys are sorted as by package
+// fmt. It doesn't affect which differences are found, only
+// the order in which they are reported.
+func MapKeyOrder[K comparable](less func(a, b K) bool) Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*K)(nil)).Elem()
+		c.keyOrder[t] = reflect.ValueOf(less)
+	}}
+}
+
+// Table compares slices of slices, such as the [][]any rows
+// of a database query result, as tables with the given
+// column names. Rows are matched by position, allowing for
+// inserted and deleted rows, or by key if TableKey is also
+// given. Matched rows are compared cell by cell, with the
+// column name in the path of each difference, as in
+//
+//	[][]any[2].name: "x" != "y"
+//
+// Rows present on only one side are reported as removed or
+// added, with each cell labeled by its column name.
+// Cells beyond the named columns are identified by index.
+func Table(columns []string) Option {
+	return Option{func(c *config) {
+		c.tableColumns = append([]string{}, columns...)
+	}}
+}
+
+// TableKey makes Table match rows by the value in the
+// named column, such as a primary key, rather than by
+// position. Keys are compared with ==, or by their
+// Go-syntax representation if they aren't comparable.
+// If several rows have the same key, they are
+// matched in order of their appearance.
+func TableKey(column string) Option {
+	return Option{func(c *config) {
+		c.tableKey = column
+	}}
+}
+
+// EqualFuncRemove removes any equal func for type T.
+// See EqualFunc.
+func EqualFuncRemove[T any]() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		delete(c.equalFunc, t)
+	}}
+}
+
+// GobEqual compares values of type T by their encoding/gob
+// encodings, and diffs the encoded bytes, rather than
+// comparing their in-memory representations.
+// This is useful to check that two values round-trip
+// identically, for example in tests of persistence code.
+// Values are equal only if their encodings are identical
+// byte for byte. Note that gob does not canonicalize maps;
+// it encodes their entries in Go's unspecified iteration
+// order, so GobEqual is not suitable for types containing
+// maps with more than one element.
+//
+// If a value can't be encoded, GobEqual writes a note with
+// the error, which is not counted as a difference, and
+// compares the values as usual.
+func GobEqual[T any]() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		c.gob[t] = true
+	}}
+}
+
 // Format customizes the description of the difference
 // between two unequal values a and b.
 //
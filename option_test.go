@@ -1,13 +1,26 @@
 package diff_test
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
 	"math"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"kr.dev/diff"
+	"kr.dev/diff/internal/pbstub/durationpb"
+	"kr.dev/diff/internal/pbstub/timestamppb"
 )
 
 func TestEqualNaN(t *testing.T) {
@@ -85,6 +98,43 @@ func TestZeroFields(t *testing.T) {
 	})
 }
 
+func TestDefaults(t *testing.T) {
+	type TLS struct {
+		Cert string
+		Min  int
+	}
+	type Config struct {
+		Host    string
+		Port    int
+		Verbose bool
+		TLS     TLS
+		name    string
+	}
+	defaults := Config{Host: "localhost", Port: 8080, TLS: TLS{Min: 12}, name: "x"}
+	opt := diff.OptionList(
+		diff.Defaults(defaults),
+		diff.Defaults(TLS{Min: 12}),
+	)
+
+	parsed := Config{Port: 8080, TLS: TLS{Cert: "c.pem"}}
+	want := Config{Host: "localhost", TLS: TLS{Cert: "c.pem", Min: 12}, name: "x"}
+	diff.Test(t, t.Errorf, parsed, want, opt)
+	testUnequal(t, parsed, want)
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, Config{Port: 9090}, Config{Verbose: true}, opt)
+	wantDiff := "diff_test.Config(transformed).Port: 9090 != 8080\n" +
+		"diff_test.Config(transformed).Verbose: false != true\n"
+	if got != wantDiff {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", wantDiff)
+	}
+
+	diff.Test(t, t.Errorf, 0, 5, diff.Defaults(5))
+}
+
 func TestKeepFields(t *testing.T) {
 	type C struct{ A, B int }
 	t0 := C{1, 2}
@@ -130,3 +180,2018 @@ func TestKeepExported(t *testing.T) {
 		t.Errorf("expected panic")
 	}
 }
+
+func TestShortDepth(t *testing.T) {
+	type T struct{ P *T }
+	a := T{}
+	b := T{P: &T{P: &T{P: &T{}}}}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.OptionList(), "diff_test.T.P: nil != {P:{...}}"},
+		{diff.ShortDepth(1), "diff_test.T.P: nil != {...}"},
+		{diff.ShortDepth(4), "diff_test.T.P: nil != {P:{P:{P:nil}}}"},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, a, b, tt.opt)
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNetIP(t *testing.T) {
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("10.0.0.2"),
+			"10.0.0.1 != 10.0.0.2",
+		},
+		{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("10.0.0.0/16"),
+			"10.0.0.0/8 != 10.0.0.0/16",
+		},
+		{
+			netip.MustParseAddrPort("[::1]:80"),
+			netip.MustParseAddrPort("[::1]:443"),
+			"[::1]:80 != [::1]:443",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+
+	a := netip.MustParseAddr("fe80::1%eth0")
+	b := netip.MustParseAddr("fe80::1%eth0")
+	diff.Test(t, t.Errorf, a, b)
+}
+
+func TestMapKeyString(t *testing.T) {
+	type K struct{ N int }
+	t0 := time.Date(2021, 1, 31, 12, 39, 0, 0, time.UTC)
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{
+			map[time.Time]int{t0: 1},
+			map[time.Time]int{t0: 2},
+			"map[time.Time]int[2021-01-31 12:39:00 +0000 UTC]: 1 != 2",
+		},
+		{
+			map[K]int{{1}: 1},
+			map[K]int{{1}: 2},
+			"map[diff_test.K]int[diff_test.K{N:1}]: 1 != 2",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.MapKeyString())
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestEqualFunc(t *testing.T) {
+	type T struct{ A, B int }
+	sameA := diff.EqualFunc(func(a, b T) bool { return a.A == b.A })
+
+	t.Run("equal", func(t *testing.T) {
+		diff.Test(t, t.Errorf, T{1, 2}, T{1, 3}, sameA)
+		diff.Test(t, t.Errorf, []T{{1, 2}}, []T{{1, 3}}, sameA)
+	})
+
+	t.Run("unequal", func(t *testing.T) {
+		want := "diff_test.T.B: 2 != 3"
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, T{1, 2}, T{1, 3}, sameA, diff.EqualFuncRemove[T]())
+		if got != want {
+			t.Errorf("diff = %q, want %q", got, want)
+		}
+
+		want = "diff_test.T{\n" + tab + "A: 1,\n" + tab + "B: 2,\n} != " +
+			"diff_test.T{\n" + tab + "A: 2,\n" + tab + "B: 2,\n}"
+		diff.Test(t, sink, T{1, 2}, T{2, 2}, sameA)
+		if got != want {
+			t.Errorf("diff = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestNoPointerShortcut(t *testing.T) {
+	// NaN is unequal to itself, so shared NaN values
+	// reveal whether the contents were compared.
+	nan := math.NaN()
+	cases := []any{
+		&nan,
+		[]float64{nan},
+		map[int]float64{0: nan},
+	}
+	for _, v := range cases {
+		diff.Test(t, t.Errorf, v, v)
+
+		equal := true
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			equal = false
+			t.Logf(format, arg...)
+		}
+		diff.Test(t, sink, v, v, diff.NoPointerShortcut())
+		if equal {
+			t.Errorf("diff %v: no difference, want NaN difference", v)
+		}
+	}
+}
+
+// decimal is a stand-in for types such as
+// shopspring/decimal.Decimal, with a coefficient and
+// exponent that have many representations of each value.
+type decimal struct {
+	coef int64
+	exp  int
+}
+
+func (d decimal) norm() decimal {
+	for d.coef != 0 && d.coef%10 == 0 {
+		d.coef /= 10
+		d.exp++
+	}
+	return d
+}
+
+func (d decimal) Equal(d2 decimal) bool { return d.norm() == d2.norm() }
+func (d decimal) String() string        { return fmt.Sprintf("%de%d", d.coef, d.exp) }
+
+func TestDecimalLike(t *testing.T) {
+	type T struct{ D decimal }
+	diff.Test(t, t.Errorf, T{decimal{10, 0}}, T{decimal{1, 1}}, diff.DecimalLike())
+
+	want := "diff_test.T.D: 10e0 != 2e1"
+	var got string
+	sink := func(format string, arg ...any) {
+		t.Helper()
+		got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+	}
+	diff.Test(t, sink, T{decimal{10, 0}}, T{decimal{2, 1}}, diff.DecimalLike())
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	type T struct{ A, B, C int }
+	cases := []struct {
+		b    T
+		want string
+	}{
+		{T{1, 2, 3}, ""},
+		{T{1, 2, 4}, "diff_test.T.C: 3 != 4\n# 1 difference\n"},
+		{T{0, 2, 4}, "diff_test.T.A: 1 != 0\ndiff_test.T.C: 3 != 4\n# 2 differences\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, T{1, 2, 3}, tt.b, diff.Summarize())
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestURLCanonical(t *testing.T) {
+	parse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	a := parse("https://example.org/a%2Fb?a=1&b=2#x%20y")
+	b := parse("https://example.org/a%2fb?b=2&a=1#x y")
+	diff.Test(t, t.Errorf, a, b, diff.URLCanonical())
+	diff.Test(t, t.Errorf, parse("/%7Ex"), parse("/~x"), diff.URLCanonical())
+
+	n := 0
+	diff.Each(func(string, ...any) (int, error) { n++; return 0, nil },
+		parse("/a%2Fb"), parse("/a/b"), diff.URLCanonical())
+	if n != 1 {
+		t.Errorf("/a%%2Fb vs /a/b: got %d differences, want 1", n)
+	}
+
+	c := parse("https://example.org/?b=3&a=1")
+	want := `"https://example.org/?a=1&b=2" != "https://example.org/?b=3&a=1"`
+	var got string
+	sink := func(format string, arg ...any) {
+		t.Helper()
+		got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+	}
+	diff.Test(t, sink, parse("https://example.org/?a=1&b=2"), c, diff.URLCanonical())
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestPromoteEmbedded(t *testing.T) {
+	type Inner struct{ X, Y int }
+	type Middle struct {
+		Inner
+		Y int
+	}
+	type T struct {
+		*Middle
+		Z int
+	}
+
+	cases := []struct {
+		a, b T
+		want string
+	}{
+		{
+			T{&Middle{Inner{X: 1}, 0}, 0},
+			T{&Middle{Inner{X: 2}, 0}, 0},
+			"diff_test.T.X: 1 != 2",
+		},
+		{
+			T{&Middle{Inner{Y: 1}, 0}, 0},
+			T{&Middle{Inner{Y: 2}, 0}, 0},
+			"diff_test.T.Inner.Y: 1 != 2", // Y is shadowed by Middle.Y
+		},
+		{
+			T{&Middle{Inner{}, 1}, 0},
+			T{&Middle{Inner{}, 2}, 0},
+			"diff_test.T.Y: 1 != 2",
+		},
+		{
+			T{nil, 0},
+			T{&Middle{}, 0},
+			"diff_test.T.Middle: nil != {\n" +
+				tab + "Inner: {...},\n" +
+				tab + "Y:     0,\n" +
+				"}",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.PromoteEmbedded())
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	sys     any
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any           { return fi.sys }
+
+func TestFileInfo(t *testing.T) {
+	type T struct{ FI fs.FileInfo }
+	t0 := time.Date(2021, 1, 31, 12, 39, 0, 0, time.UTC)
+	a := T{&fileInfo{"a", 3, 0o644, t0, 1}}
+
+	diff.Test(t, t.Errorf, a, T{&fileInfo{"a", 3, 0o644, t0, 2}}, diff.FileInfo())
+	diff.Test(t, t.Errorf, a, T{&fileInfo{"a", 3, 0o644, t0.Add(time.Second), 2}},
+		diff.FileInfoWithin(time.Second))
+
+	b := T{&fileInfo{"b", 4, fs.ModeDir | 0o755, t0.Add(time.Second), 1}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.FileInfo())
+	want := `diff_test.T.FI.Name(): "a" != "b"` + "\n" +
+		"diff_test.T.FI.Size(): 3 != 4\n" +
+		"diff_test.T.FI.Mode(): -rw-r--r-- != drwxr-xr-x\n" +
+		"diff_test.T.FI.ModTime(): 2021-01-31T12:39:00Z != 2021-01-31T12:39:01Z (1s)\n" +
+		"diff_test.T.FI.IsDir(): false != true\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestDurationDelta(t *testing.T) {
+	type T struct {
+		D time.Duration
+		N int64
+	}
+	a := T{time.Hour + 2*time.Minute, 1}
+	b := T{time.Hour + 5*time.Minute, 2}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.DurationDelta)
+	want := "diff_test.T.D: 1h2m0s != 1h5m0s (Δ+3m0s)\n" +
+		"diff_test.T.N: 1 != 2\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, b.D, a.D, diff.DurationDelta)
+	want = "1h5m0s != 1h2m0s (Δ-3m0s)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestJSONNumbers(t *testing.T) {
+	a := map[string]any{"n": 5.0, "l": []any{1.0, 2.0}, "u": 7.0}
+	b := map[string]any{"n": 5, "l": []any{int64(1), 2}, "u": uint8(7)}
+	diff.Test(t, t.Errorf, a, b, diff.JSONNumbers())
+
+	cases := []struct {
+		a, b any
+	}{
+		{map[string]any{"n": 5.5}, map[string]any{"n": 5}},
+		{map[string]any{"n": 5.0}, map[string]any{"n": "5"}},
+		{map[string]any{"n": float32(5)}, map[string]any{"n": 5}},
+		{map[string]any{"n": 1e20}, map[string]any{"n": int64(math.MaxInt64)}},
+		{struct{ F float64 }{5}, struct{ F float64 }{6}},
+	}
+	for _, tt := range cases {
+		equal := true
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			equal = false
+			t.Logf(format, arg...)
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.JSONNumbers())
+		if equal {
+			t.Errorf("diff %v %v: no diff, want diff", tt.a, tt.b)
+		}
+	}
+}
+
+func TestNumericCrossKind(t *testing.T) {
+	a := map[string]any{
+		"i":  int64(5),
+		"u":  uint8(7),
+		"f":  2.0,
+		"f2": float32(0.5),
+		"l":  []any{1, int32(-2)},
+	}
+	b := map[string]any{
+		"i":  5,
+		"u":  7,
+		"f":  int16(2),
+		"f2": 0.5,
+		"l":  []any{uint(1), int8(-2)},
+	}
+	diff.Test(t, t.Errorf, a, b, diff.NumericCrossKind())
+
+	type Duration int64
+	cases := []struct {
+		a, b any
+	}{
+		{map[string]any{"n": 5.5}, map[string]any{"n": 5}},
+		{map[string]any{"n": int64(5)}, map[string]any{"n": int64(6)}},
+		{map[string]any{"n": -1}, map[string]any{"n": uint64(math.MaxUint64)}},
+		{map[string]any{"n": int8(-1)}, map[string]any{"n": uint8(255)}},
+		{map[string]any{"n": 1e20}, map[string]any{"n": int64(math.MaxInt64)}},
+		{map[string]any{"n": float64(math.MaxUint64)}, map[string]any{"n": uint64(math.MaxUint64)}},
+		{map[string]any{"n": float32(0.1)}, map[string]any{"n": 0.1}},
+		{map[string]any{"n": Duration(5)}, map[string]any{"n": int64(5)}},
+		{map[string]any{"n": 5}, map[string]any{"n": "5"}},
+		{struct{ N int }{5}, struct{ N int64 }{5}},
+	}
+	for _, tt := range cases {
+		equal := true
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			equal = false
+			t.Logf(format, arg...)
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.NumericCrossKind())
+		if equal {
+			t.Errorf("diff %v %v: no diff, want diff", tt.a, tt.b)
+		}
+	}
+}
+
+func TestEqualNaNAll(t *testing.T) {
+	nan := math.NaN()
+	type Float float64
+	cases := [][2]any{
+		{float32(nan), float32(nan)},
+		{Float(nan), Float(nan)},
+		{complex(1, nan), complex(1, nan)},
+		{complex64(complex(nan, 2)), complex64(complex(nan, 2))},
+		{[]float64{1, nan, 3}, []float64{1, nan, 3}},
+		{map[string]float32{"x": float32(nan)}, map[string]float32{"x": float32(nan)}},
+	}
+	for _, tt := range cases {
+		diff.Test(t, t.Errorf, tt[0], tt[1], diff.EqualNaNAll())
+		testUnequal(t, tt[0], tt[1])
+	}
+
+	unequal := [][2]any{
+		{float32(nan), float32(1)},
+		{complex(1, nan), complex(2, nan)},
+		{complex(nan, 1), complex(1, nan)},
+	}
+	for _, tt := range unequal {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt[0], tt[1], diff.EqualNaNAll())
+		if got == "" {
+			t.Errorf("diff %v %v: no diff, want diff", tt[0], tt[1])
+		}
+	}
+
+	// Element equality must match NaN elements,
+	// so the only difference is the added element.
+	a := []float32{float32(nan), 1}
+	b := []float32{0, float32(nan), 1}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EqualNaNAll())
+	want := "[]float32[0]: (added) 0\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestNilEmptyEqual(t *testing.T) {
+	cases := [][2]any{
+		{[]int(nil), []int{}},
+		{map[int]int(nil), map[int]int{}},
+		{[]byte(nil), []byte{}},
+		{
+			[]map[string]any{nil, {"x": []int{}}, {"y": 1}},
+			[]map[string]any{{}, {"x": []int(nil)}, {"y": 1}},
+		},
+		{
+			[][]int{{1}, nil, {2}},
+			[][]int{{1}, {}, {2}},
+		},
+	}
+	for _, tt := range cases {
+		diff.Test(t, t.Errorf, tt[0], tt[1], diff.NilEmptyEqual())
+		testUnequal(t, tt[0], tt[1])
+	}
+
+	// Element equality must match nil and empty elements,
+	// so the only difference is the added element.
+	a := [][]int{{1}, nil, {2}}
+	b := [][]int{{0}, {1}, {}, {2}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.NilEmptyEqual())
+	want := "[][]int[0]: (added) {0}\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	testUnequal(t, []int(nil), []int{1})
+	var got2 string
+	gotp = (*stringPrinter)(&got2)
+	diff.Each(gotp.Printf, []int(nil), []int{1}, diff.NilEmptyEqual())
+	if got2 == "" {
+		t.Errorf("nil and non-empty: no diff, want diff")
+	}
+}
+
+func TestMapAbsentIsZero(t *testing.T) {
+	cases := [][2]any{
+		{map[string]int{"a": 1}, map[string]int{"a": 1, "b": 0}},
+		{map[string]int{"a": 0}, map[string]int{"b": 0}},
+		{map[string]int(nil), map[string]int{"a": 0}},
+		{map[string]*int{"a": nil}, map[string]*int{}},
+		{
+			[]map[string]string{{"a": ""}},
+			[]map[string]string{{}},
+		},
+	}
+	for _, tt := range cases {
+		diff.Test(t, t.Errorf, tt[0], tt[1], diff.MapAbsentIsZero())
+		testUnequal(t, tt[0], tt[1])
+	}
+
+	a := map[string]any{"a": 0, "b": nil, "c": 1}
+	b := map[string]any{}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MapAbsentIsZero())
+	want := "map[string]any[\"a\"]: (removed)\n" +
+		"map[string]any[\"c\"]: (removed)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	var got2 string
+	gotp = (*stringPrinter)(&got2)
+	diff.Each(gotp.Printf, map[string]int(nil), map[string]int{"a": 1}, diff.MapAbsentIsZero())
+	if got2 == "" {
+		t.Errorf("nil and non-zero: no diff, want diff")
+	}
+}
+
+func TestPerContainerLimit(t *testing.T) {
+	type T struct {
+		A []int
+		M map[string]int
+	}
+	a := T{
+		A: []int{1, 2, 3, 4, 5},
+		M: map[string]int{"a": 1, "b": 2, "c": 3, "d": 4},
+	}
+	b := T{
+		A: []int{0, 0, 0, 0, 5, 6},
+		M: map[string]int{"a": 0, "b": 2, "c": 0},
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.PerContainerLimit(2))
+	want := "diff_test.T.A[0]: 1 != 0\n" +
+		"diff_test.T.A[1]: 2 != 0\n" +
+		"diff_test.T.A: (+ 3 more differing elements)\n" +
+		"diff_test.T.M[\"a\"]: 1 != 0\n" +
+		"diff_test.T.M[\"c\"]: 3 != 0\n" +
+		"diff_test.T.M: (+ 1 more differing elements)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.PerContainerLimit(10))
+	if strings.Contains(got, "more differing") {
+		t.Errorf("diff = %q, want no summary", got)
+	}
+
+	ids := func(v int) any { return v }
+	rows := [][]any{{1}, {2}, {3}, {4}}
+	cases := []struct {
+		name string
+		a, b any
+		opt  diff.Option
+		want string
+	}{
+		{"KeyedSlice", []int{1, 2, 3, 4}, []int{5, 6, 7},
+			diff.KeyedSlice(ids),
+			"[]int[0]: (removed) 1\n" +
+				"[]int[1]: (removed) 2\n" +
+				"[]int: (+ 5 more differing elements)\n"},
+		{"MapValuesAsSet", map[int]int{1: 1, 2: 2, 3: 3}, map[int]int{},
+			diff.MapValuesAsSet(),
+			"map[int]int: (removed) 1\n" +
+				"map[int]int: (removed) 2\n" +
+				"map[int]int: (+ 1 more differing elements)\n"},
+		{"Table", rows, [][]any{},
+			diff.Table([]string{"id"}),
+			"[][]any[0]: (removed) {id: int(1)}\n" +
+				"[][]any[1]: (removed) {id: int(2)}\n" +
+				"[][]any: (+ 2 more differing elements)\n"},
+	}
+	for _, tt := range cases {
+		got = ""
+		diff.Each(gotp.Printf, tt.a, tt.b, tt.opt, diff.PerContainerLimit(2))
+		if got != tt.want {
+			t.Errorf("%s: diff = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStructByFieldName(t *testing.T) {
+	a := struct{ A, B int }{1, 2}
+	b := struct{ B, A int }{2, 1}
+	diff.Test(t, t.Errorf, a, b, diff.StructByFieldName())
+	testUnequal(t, a, b)
+
+	type C struct {
+		A int
+		B string
+		C bool
+	}
+	type D struct {
+		D int
+		B string
+		A int
+	}
+	c := C{1, "x", true}
+	d := D{4, "y", 1}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, c, d, diff.StructByFieldName())
+	want := `diff_test.C.B: "x" != "y"` + "\n" +
+		"diff_test.C.C: (removed)\n" +
+		"diff_test.C.D: (added) 4\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestComplexTolerance(t *testing.T) {
+	type T struct {
+		C64  complex64
+		C128 complex128
+	}
+	a := T{1 + 1i, 1 + 1i}
+	b := T{1 + 1.05i, 1.06 + 1i}
+	diff.Test(t, t.Errorf, a, b, diff.ComplexTolerance(0.1))
+	testUnequal(t, a, b)
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, T{1 + 1i, 1 + 2i}, diff.ComplexTolerance(0.1))
+	want := "diff_test.T.C128: (1+1i) != (1+2i) (|Δ|=1)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestAlwaysShowType(t *testing.T) {
+	type T struct{ P *int }
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{[]int{0}, []int{1}, "[]int[0]: int(0) != int(1)"},
+		{map[int]int{}, map[int]int{1: 1}, "map[int]int[1]: (added) int(1)"},
+		{T{}, T{new(int)}, "diff_test.T.P: (*int)(nil) != &int(0)"},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.AlwaysShowType())
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestTypeForKinds(t *testing.T) {
+	type T struct{ P *int }
+	type U struct{ A int }
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{[]int{0}, []int{1}, "[]int[0]: 0 != 1"},
+		{map[int]U{}, map[int]U{1: {1}}, "map[int]diff_test.U[1]: (added) diff_test.U{A:1}"},
+		{T{}, T{new(int)}, "diff_test.T.P: (*int)(nil) != &int(0)"},
+	}
+	for _, tt := range cases {
+		var got string
+		sink := func(format string, arg ...any) {
+			t.Helper()
+			got = strings.TrimSpace(fmt.Sprintf(format, arg...))
+		}
+		diff.Test(t, sink, tt.a, tt.b, diff.TypeForKinds(reflect.Struct, reflect.Pointer))
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	r := image.Rect(0, 0, 4, 4)
+	a := image.NewRGBA(r)
+	b := image.NewNRGBA(r) // different concrete type
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			a.Set(x, y, color.RGBA{100, 100, 100, 255})
+			b.Set(x, y, color.NRGBA{102, 100, 100, 255})
+		}
+	}
+	type T struct{ I image.Image }
+	diff.Test(t, t.Errorf, T{a}, T{b}, diff.Image(2))
+
+	b.Set(1, 2, color.NRGBA{100, 90, 100, 255})
+	b.Set(3, 3, color.NRGBA{100, 95, 100, 255})
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{a}, T{b}, diff.Image(2))
+	want := "diff_test.T.I: 2 pixels differ (max Δ=10 at (1,2))\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	c := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	diff.Each(gotp.Printf, T{a}, T{c}, diff.Image(2))
+	want = "diff_test.T.I: bounds (0,0)-(4,4) != (0,0)-(2,2)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestCollapseRanges(t *testing.T) {
+	a := []int{1, 2, 3, 4, 5, 6}
+	b := []int{1, 7, 8, 9, 5, 0}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.CollapseRanges())
+	want := "[]int[1:4]: {\n" +
+		tab + "2,\n" +
+		tab + "3,\n" +
+		tab + "4,\n" +
+		"} != {\n" +
+		tab + "7,\n" +
+		tab + "8,\n" +
+		tab + "9,\n" +
+		"}\n" +
+		"[]int[5]: 6 != 0\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf,
+		map[string][4]int{"k": {1, 2, 3, 4}},
+		map[string][4]int{"k": {1, 7, 8, 4}},
+		diff.CollapseRanges())
+	want = "map[string][4]int[\"k\"][1:3]: {\n" +
+		tab + "2,\n" +
+		tab + "3,\n" +
+		"} != {\n" +
+		tab + "7,\n" +
+		tab + "8,\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var got string
+	f := func(format string, arg ...any) {
+		got += fmt.Sprintf(format, arg...)
+	}
+	opt := diff.Labels("(only in want)", "(only in got)")
+	diff.Test(t, f, map[string]int{"a": 1}, map[string]int{"b": 2}, opt)
+	diff.Test(t, f, []int{1, 2}, []int{1}, opt)
+	diff.Test(t, f, []int{1}, []int{1, 2}, opt)
+	want := "map[string]int[\"a\"]: (only in got)\n" +
+		"map[string]int[\"b\"]: (only in want) 2\n" +
+		"[]int[1]: (only in got) 2\n" +
+		"[]int[1]: (only in want) 2\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	type T struct{ A int }
+	var got string
+	sink := func(format string, arg ...any) { got += fmt.Sprintf(format, arg...) }
+	labels := diff.WithLabels("expected", "actual")
+
+	diff.Test(t, sink, T{1}, T{2}, labels, diff.EmitFull)
+	want := "diff_test.T:\n" +
+		"expected.A:\n" +
+		tab + "int(1)\n" +
+		"actual.A:\n" +
+		tab + "int(2)\n"
+	if got != want {
+		t.Errorf("full output = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Test(t, sink, "x\ny\nz", "x\nw\nz", labels)
+	want = "--- expected\n" +
+		"+++ actual\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-y\n" +
+		"+w\n" +
+		" z\n\n"
+	if got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestDetectMoves(t *testing.T) {
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{
+			[]int{1, 2, 3, 4, 5, 6, 7, 8},
+			[]int{1, 2, 4, 5, 6, 7, 8, 3},
+			"[]int: moved [2]->[7] 3\n",
+		},
+		{
+			[]int{7, 1, 2, 3},
+			[]int{1, 7, 2, 3, 7},
+			"[]int: moved [0]->[1] 7\n" +
+				"[]int[4]: (added) 7\n",
+		},
+		{
+			[]int{1, 2},
+			[]int{3, 1},
+			"[]int[0]: (added) 3\n" +
+				"[]int[1]: (removed) 2\n",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.DetectMoves())
+		if got != tt.want {
+			t.Errorf("diff.Each(%v, %v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestProtoTime(t *testing.T) {
+	type T struct {
+		At  *timestamppb.Timestamp
+		Dur *durationpb.Duration
+	}
+	a := T{
+		&timestamppb.Timestamp{Seconds: 1e9, Nanos: 5},
+		&durationpb.Duration{Seconds: 60},
+	}
+	b := T{
+		&timestamppb.Timestamp{Seconds: 1e9 + 2, Nanos: 5},
+		&durationpb.Duration{Seconds: 90},
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, a, diff.ProtoTime(), diff.DurationDelta)
+	diff.Each(gotp.Printf, a, b, diff.ProtoTime(), diff.DurationDelta)
+	want := "diff_test.T.At(transformed): " +
+		"2001-09-09T01:46:40.000000005Z != 2001-09-09T01:46:42.000000005Z (2s)\n" +
+		"diff_test.T.Dur(transformed): 1m0s != 1m30s (Δ+30s)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestMapValuesAsSet(t *testing.T) {
+	a := map[string]int{"x": 1, "y": 2, "z": 2, "w": 3}
+	b := map[string]int{"p": 2, "q": 1, "r": 4, "s": 3}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, a, diff.MapValuesAsSet())
+	diff.Each(gotp.Printf, a, b, diff.MapValuesAsSet())
+	want := "map[string]int: (removed) 2\n" +
+		"map[string]int: (added) 4\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestOnlyPaths(t *testing.T) {
+	type Item struct{ Name, Desc string }
+	type T struct {
+		ID    int
+		Items []Item
+		Tags  map[string]string
+		Inner struct{ A, B int }
+	}
+	a := T{
+		ID:    1,
+		Items: []Item{{"a", "x"}, {"b", "y"}},
+		Tags:  map[string]string{"env": "prod", "team": "a"},
+	}
+	b := T{
+		ID:    2,
+		Items: []Item{{"a", "z"}, {"c", "w"}},
+		Tags:  map[string]string{"env": "dev", "team": "b"},
+	}
+	b.Inner.A, b.Inner.B = 1, 2
+
+	cases := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{".ID"}, "diff_test.T.ID: 1 != 2\n"},
+		{[]string{".Inner"}, "diff_test.T.Inner.A: 0 != 1\ndiff_test.T.Inner.B: 0 != 2\n"},
+		{[]string{".Items[*].Name"}, "diff_test.T.Items[1].Name: \"b\" != \"c\"\n"},
+		{[]string{`.Tags["env"]`, ".Inner.B"}, "diff_test.T.Tags[\"env\"]: \"prod\" != \"dev\"\ndiff_test.T.Inner.B: 0 != 2\n"},
+		{[]string{".I"}, ""},
+		{[]string{}, ""},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, diff.OnlyPaths(tt.paths...))
+		if got != tt.want {
+			t.Errorf("OnlyPaths(%q) diff = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestOnlyPathsTransformed(t *testing.T) {
+	type T struct {
+		ID      int
+		Created time.Time
+	}
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := T{ID: 1, Created: t0}
+	b := T{ID: 2, Created: t0.Add(time.Second)}
+	for _, path := range []string{".Created", ".C*"} {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, diff.OnlyPaths(path))
+		want := "diff_test.T.Created(transformed): " +
+			"2024-01-02T03:04:05Z != 2024-01-02T03:04:06Z (1s)\n"
+		if got != want {
+			t.Errorf("OnlyPaths(%q) diff = %q, want %q", path, got, want)
+		}
+	}
+}
+
+type schema struct {
+	Cols []string
+}
+
+func (s schema) Diff(o schema) []string {
+	var d []string
+	if len(s.Cols) != len(o.Cols) {
+		d = append(d, fmt.Sprintf("%d columns != %d columns", len(s.Cols), len(o.Cols)))
+	}
+	return d
+}
+
+type version struct{ Major, Minor int }
+
+func (v version) Equal(o version) bool { return v.Major == o.Major }
+
+func (v version) Diff(o version) string {
+	return fmt.Sprintf("v%d != v%d", v.Major, o.Major)
+}
+
+func TestUseDiffMethod(t *testing.T) {
+	type T struct {
+		S schema
+		V version
+	}
+	a := T{schema{[]string{"a", "b"}}, version{1, 0}}
+	b := T{schema{[]string{"c"}}, version{2, 0}}
+	c := T{schema{[]string{"x", "y"}}, version{1, 5}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.UseDiffMethod())
+	diff.Each(gotp.Printf, a, c, diff.UseDiffMethod())
+	want := "diff_test.T.S: 2 columns != 1 columns\n" +
+		"diff_test.T.V: v1 != v2\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, c, diff.UseDiffMethod(), diff.EqualFunc(func(a, b schema) bool {
+		return a.Cols[0] == b.Cols[0]
+	}))
+	want = "diff_test.T.S: {Cols:{...}} != {Cols:{...}}\n"
+	if got != want {
+		t.Errorf("diff with EqualFunc = %q, want %q", got, want)
+	}
+}
+
+func TestNumberPercent(t *testing.T) {
+	type T struct {
+		CPU  int
+		Mem  uint
+		Load float64
+		Errs int
+	}
+	a := T{CPU: 100, Mem: 200, Load: 0.8, Errs: 0}
+	b := T{CPU: 130, Mem: 150, Load: 0.9, Errs: 5}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.NumberPercent)
+	want := "diff_test.T.CPU: 100 != 130 (+30%)\n" +
+		"diff_test.T.Mem: 200 != 150 (-25%)\n" +
+		"diff_test.T.Load: 0.8 != 0.9 (+12.5%)\n" +
+		"diff_test.T.Errs: 0 != 5 (Δ+5)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestKeyedSlice(t *testing.T) {
+	type User struct {
+		ID   int
+		Name string
+	}
+	a := []User{{1, "ann"}, {2, "bob"}, {3, "cy"}, {3, "cy2"}}
+	b := []User{{4, "dee"}, {3, "cy"}, {1, "ann"}, {2, "rob"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	key := diff.KeyedSlice(func(u User) any { return u.ID })
+	diff.Each(gotp.Printf, a, b, key)
+	want := "[]diff_test.User[1].Name: \"bob\" != \"rob\"\n" +
+		"[]diff_test.User[3]: (removed) {\n" +
+		tab + "ID:   3,\n" +
+		tab + "Name: \"cy2\",\n" +
+		"}\n" +
+		"[]diff_test.User[0]: (added) {\n" +
+		tab + "ID:   4,\n" +
+		tab + "Name: \"dee\",\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestKeyedSliceNotComparable(t *testing.T) {
+	defer func() {
+		const want = "diff: KeyedSlice key of type []int is not comparable"
+		if r := recover(); r != want {
+			t.Errorf("panic = %v, want %q", r, want)
+		}
+	}()
+	key := diff.KeyedSlice(func(v []int) any { return v })
+	diff.Each(func(string, ...any) (int, error) { return 0, nil },
+		[][]int{{1}}, [][]int{{2}}, key)
+}
+
+func TestTimeInstantWithZone(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	est := time.FixedZone("EST", -5*3600)
+	cases := []struct {
+		a, b time.Time
+		want string
+	}{
+		{t0, t0, ""},
+		{t0, t0.In(est), "(zones differ: UTC vs EST)\n"},
+		{t0, t0.Add(time.Second), "time.Time(transformed): 2020-01-02T03:04:05Z != 2020-01-02T03:04:06Z (1s)\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.TimeInstantWithZone())
+		if got != tt.want {
+			t.Errorf("diff.Each(%v, %v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	cases := []struct {
+		a, b []string
+		want string
+	}{
+		{[]string{"x"}, []string{"x", "a\nb"}, "[]string[1]: (added) `a\nb`\n"},
+		{[]string{"x"}, []string{"x", "a\n`b`"}, "[]string[1]: (added) \"a\\n`b`\"\n"},
+		{[]string{"x"}, []string{"x", "a\r\nb"}, "[]string[1]: (added) \"a\\r\\nb\"\n"},
+		{[]string{"x"}, []string{"x", "ab"}, "[]string[1]: (added) \"ab\"\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.RawStrings())
+		if got != tt.want {
+			t.Errorf("diff.Each(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRespectTags(t *testing.T) {
+	type T struct {
+		ID      int
+		Created time.Time `diff:"ignore"`
+		Cache   []byte    `diff:"-"`
+		Tags    []string  `diff:",unordered"`
+		Other   int       `check:"-"`
+	}
+	a := T{ID: 1, Created: time.Unix(1, 0), Cache: []byte("x"), Tags: []string{"a", "b", "c"}, Other: 1}
+	b := T{ID: 1, Created: time.Unix(2, 0), Cache: []byte("y"), Tags: []string{"c", "a", "d"}, Other: 2}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.RespectTags(""))
+	want := "diff_test.T.Tags: (removed) \"b\"\n" +
+		"diff_test.T.Tags: (added) \"d\"\n" +
+		"diff_test.T.Other: 1 != 2\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.RespectTags("check"))
+	want = "diff_test.T.Created(transformed): " +
+		"1970-01-01T00:00:01Z != 1970-01-01T00:00:02Z (1s)\n" +
+		"diff_test.T.Cache: \"x\" != \"y\"\n" +
+		"diff_test.T.Tags[0]: (removed) \"a\"\n" +
+		"diff_test.T.Tags[1]: (removed) \"b\"\n" +
+		"diff_test.T.Tags[3]: (added) \"a\"\n" +
+		"diff_test.T.Tags[3]: (added) \"d\"\n"
+	if got != want {
+		t.Errorf("diff with key check = %q, want %q", got, want)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	type T struct{ R *regexp.Regexp }
+	cases := []struct {
+		a, b *regexp.Regexp
+		want string
+	}{
+		{regexp.MustCompile("a+b"), regexp.MustCompile("a+b"), ""},
+		{regexp.MustCompile("a+b"), regexp.MustCompile("a*b"), "diff_test.T.R(transformed): \"a+b\" != \"a*b\"\n"},
+		{nil, regexp.MustCompile("a"), "diff_test.T.R(transformed): nil != \"a\"\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, T{tt.a}, T{tt.b}, diff.Regexp())
+		if got != tt.want {
+			t.Errorf("diff.Each(%v, %v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+type flags uint16
+
+func TestNumberBase(t *testing.T) {
+	type T struct {
+		F flags
+		N int
+	}
+	a := T{F: 0x10, N: -5}
+	b := T{F: 0x30, N: 6}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.NumberBase(16), "diff_test.T.F: 0x10 != 0x30\ndiff_test.T.N: -0x5 != 0x6\n"},
+		{diff.NumberBase(2), "diff_test.T.F: 0b10000 != 0b110000\ndiff_test.T.N: -0b101 != 0b110\n"},
+		{diff.NumberBase(8), "diff_test.T.F: 0o20 != 0o60\ndiff_test.T.N: -0o5 != 0o6\n"},
+		{diff.NumberBaseFor[flags](16), "diff_test.T.F: 0x10 != 0x30\ndiff_test.T.N: -5 != 6\n"},
+		{
+			diff.OptionList(diff.NumberBase(2), diff.NumberBaseFor[flags](16)),
+			"diff_test.T.F: 0x10 != 0x30\ndiff_test.T.N: -0b101 != 0b110\n",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, tt.opt)
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSlicePrefix(t *testing.T) {
+	cases := []struct {
+		got, want []int
+		diff      string
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}, ""},
+		{[]int{1, 2, 3}, []int{1, 2}, ""},
+		{[]int{1, 2, 3}, []int{}, ""},
+		{[]int{1, 5, 3}, []int{1, 2}, "[]int[1]: 5 != 2\n"},
+		{[]int{1}, []int{1, 2}, "[]int[1]: (added) 2\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		f := func(format string, arg ...any) {
+			got += fmt.Sprintf(format, arg...)
+		}
+		diff.Test(t, f, tt.got, tt.want, diff.SlicePrefix())
+		if got != tt.diff {
+			t.Errorf("diff.Test(%v, %v) = %q, want %q", tt.got, tt.want, got, tt.diff)
+		}
+	}
+}
+
+func TestGroupByPrefix(t *testing.T) {
+	type TLS struct{ Cert, Key string }
+	type Server struct {
+		Host string
+		TLS  TLS
+	}
+	type Config struct {
+		Name   string
+		Server Server
+		Ports  []int
+	}
+	a := Config{"a", Server{"h1", TLS{"c1", "k1"}}, []int{1}}
+	b := Config{"b", Server{"h2", TLS{"c2", "k2"}}, []int{2}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.GroupByPrefix(), diff.Summarize())
+	want := "diff_test.Config.Name: \"a\" != \"b\"\n" +
+		"diff_test.Config.Server:\n" +
+		tab + ".Host: \"h1\" != \"h2\"\n" +
+		tab + ".TLS:\n" +
+		tab + tab + ".Cert: \"c1\" != \"c2\"\n" +
+		tab + tab + ".Key: \"k1\" != \"k2\"\n" +
+		"diff_test.Config.Ports[0]: 1 != 2\n" +
+		"# 5 differences\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, 1, 2, diff.GroupByPrefix())
+	want = "int(1) != int(2)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestRootTypeHook(t *testing.T) {
+	type T struct{ A int }
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{T{1}, T{2}, "diff_test.T"},
+		{[]T{{1}}, []T{{2}}, "[]diff_test.T"},
+		{1, 2, ""},
+	}
+	for _, tt := range cases {
+		var got []string
+		hook := diff.RootTypeHook(func(s string) { got = append(got, s) })
+		diff.Each(nopPrintf, tt.a, tt.b, hook)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("hook called with %q, want [%q]", got, tt.want)
+		}
+	}
+}
+
+func TestRequireNoSharing(t *testing.T) {
+	type T struct {
+		P *int
+		M map[string]int
+		S []int
+		Q *int
+	}
+	n := 1
+	m := map[string]int{"a": 1}
+	a := T{P: &n, M: m, S: []int{1}, Q: ptr(2)}
+	shallow := a
+	shallow.Q = ptr(2)
+	deep := T{P: ptr(1), M: map[string]int{"a": 1}, S: []int{1}, Q: ptr(2)}
+	cross := deep
+	cross.Q = &n
+
+	cases := []struct {
+		b    T
+		want string
+	}{
+		{shallow, "diff_test.T.P: (shared pointer)\n" +
+			"diff_test.T.M: (shared pointer)\n" +
+			"diff_test.T.S: (shared pointer)\n"},
+		{deep, ""},
+		{cross, "diff_test.T.Q: (shared pointer)\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, tt.b, diff.RequireNoSharing())
+		if got != tt.want {
+			t.Errorf("diff = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCompareStructTags(t *testing.T) {
+	a := []any{struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}{"x", 1}}
+	b := []any{struct {
+		Name string `json:"name,omitempty"`
+		ID   int    `json:"id"`
+	}{"x", 2}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.CompareStructTags())
+	want := `[]any[0].Name(tag): "json:\"name\"" != "json:\"name,omitempty\""` + "\n" +
+		"[]any[0].ID: 1 != 2\n"
+	if got != want {
+		t.Errorf("Each(CompareStructTags) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b)
+	if !strings.Contains(got, " != ") || strings.Contains(got, "(tag)") {
+		t.Errorf("Each() = %q, want type mismatch", got)
+	}
+}
+
+type orderedMap struct {
+	keys []string
+	m    map[string]int
+}
+
+func (om *orderedMap) set(k string, v int) {
+	if _, ok := om.m[k]; !ok {
+		om.keys = append(om.keys, k)
+	}
+	if om.m == nil {
+		om.m = map[string]int{}
+	}
+	om.m[k] = v
+}
+
+func TestOrderedMapLike(t *testing.T) {
+	entries := func(om orderedMap) [][2]any {
+		var kv [][2]any
+		for _, k := range om.keys {
+			kv = append(kv, [2]any{k, om.m[k]})
+		}
+		return kv
+	}
+	var a, b, c orderedMap
+	a.set("x", 1)
+	a.set("y", 2)
+	b.set("x", 1)
+	b.set("y", 3)
+	c.set("y", 2)
+	c.set("x", 1)
+
+	cases := []struct {
+		b    orderedMap
+		want string
+	}{
+		{a, ""},
+		{b, "diff_test.orderedMap(transformed)[1][1]: int(2) != int(3)\n"},
+		{c, "diff_test.orderedMap(transformed)[0]: (removed) {\n" + tab + "\"x\",\n" + tab + "int(1),\n}\n" +
+			"diff_test.orderedMap(transformed)[2]: (added) {\n" + tab + "\"x\",\n" + tab + "int(1),\n}\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, tt.b, diff.OrderedMapLike(entries))
+		if got != tt.want {
+			t.Errorf("Each(OrderedMapLike) = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// Duration has the same name as time.Duration,
+// for testing ShortTypeNames.
+type Duration int64
+
+func TestShortTypeNames(t *testing.T) {
+	type T struct{ A any }
+	cases := []struct {
+		a, b any
+		want string
+	}{
+		{T{time.Duration(1)}, T{time.Duration(2)}, "T.A: Duration(1ns) != Duration(2ns)\n"},
+		{T{time.Duration(1)}, T{time.Month(1)}, "T.A: Duration(1ns) != Month(January)\n"},
+		{T{time.Duration(1)}, T{Duration(1)}, "T.A: time.Duration(1ns) != diff_test.Duration(1)\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.ShortTypeNames())
+		if got != tt.want {
+			t.Errorf("Each(ShortTypeNames) = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+type lazy struct {
+	once sync.Once
+	f    func() int
+	v    int
+}
+
+func (l *lazy) Load() int {
+	l.once.Do(func() { l.v = l.f() })
+	return l.v
+}
+
+func TestAccessorEqual(t *testing.T) {
+	type T struct {
+		N *lazy
+		S string
+	}
+	get := func(l *lazy) any { return l.Load() }
+	a := T{&lazy{f: func() int { return 1 }}, "x"}
+	b := T{&lazy{f: func() int { return 1 }}, "x"}
+	c := T{&lazy{f: func() int { return 2 }}, "x"}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.AccessorEqual(get))
+	if got != "" {
+		t.Errorf("Each(a, b) = %q, want empty", got)
+	}
+	diff.Each(gotp.Printf, a, c, diff.AccessorEqual(get))
+	want := "diff_test.T.N: 1 != 2\n"
+	if got != want {
+		t.Errorf("Each(a, c) = %q, want %q", got, want)
+	}
+
+	got = ""
+	self := func(l *lazy) any { return l }
+	diff.Each(gotp.Printf, a, c, diff.AccessorEqual(self))
+	if got == "" {
+		t.Errorf("Each(a, c) with self accessor = %q, want differences", got)
+	}
+}
+
+func TestMapKeyOrder(t *testing.T) {
+	type Level string
+	rank := map[Level]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+	less := func(a, b Level) bool { return rank[a] < rank[b] }
+	a := map[Level]int{"debug": 1, "info": 1, "warn": 1, "error": 1}
+	b := map[Level]int{"debug": 2, "info": 2, "warn": 2, "error": 2}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MapKeyOrder(less))
+	want := `map[diff_test.Level]int["debug"]: 1 != 2` + "\n" +
+		`map[diff_test.Level]int["info"]: 1 != 2` + "\n" +
+		`map[diff_test.Level]int["warn"]: 1 != 2` + "\n" +
+		`map[diff_test.Level]int["error"]: 1 != 2` + "\n"
+	if got != want {
+		t.Errorf("Each(MapKeyOrder) = %q, want %q", got, want)
+	}
+
+	got = ""
+	type T struct{ M map[Level]int }
+	diff.Each(gotp.Printf, T{a}, T{}, diff.MapKeyOrder(less), diff.EmitFull)
+	want = "diff_test.T:\n" +
+		"a.M:\n" +
+		tab + "map[diff_test.Level]int{\n" +
+		tab + tab + `"debug": 1,` + "\n" +
+		tab + tab + `"info":  1,` + "\n" +
+		tab + tab + `"warn":  1,` + "\n" +
+		tab + tab + `"error": 1,` + "\n" +
+		tab + "}\n" +
+		"b.M:\n" +
+		tab + "map[diff_test.Level]int(nil)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+type isShape interface{ isShape() }
+
+type shapeCircle struct{ R int }
+
+type shapeSquare struct{ Side int }
+
+func (shapeCircle) isShape() {}
+func (shapeSquare) isShape() {}
+
+func TestOneof(t *testing.T) {
+	type Msg struct{ Shape isShape }
+	cases := []struct {
+		a, b Msg
+		want string
+	}{
+		{Msg{shapeCircle{1}}, Msg{shapeCircle{1}}, ""},
+		{Msg{shapeCircle{1}}, Msg{shapeCircle{2}}, "diff_test.Msg.Shape.R: 1 != 2\n"},
+		{Msg{shapeCircle{1}}, Msg{shapeSquare{1}}, "diff_test.Msg.Shape: a has case diff_test.shapeCircle, b has case diff_test.shapeSquare\n"},
+		{Msg{shapeCircle{1}}, Msg{}, "diff_test.Msg.Shape: diff_test.shapeCircle{R:1} != nil\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.Oneof())
+		if got != tt.want {
+			t.Errorf("Each(%v, %v, Oneof) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreFuncs(t *testing.T) {
+	type Server struct {
+		Name    string
+		Handler func(string) error
+		OnClose func()
+	}
+	h := func(string) error { return nil }
+	a := Server{Name: "a", Handler: h, OnClose: func() {}}
+	b := Server{Name: "b", Handler: nil, OnClose: func() {}}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.IgnoreFuncs())
+	want := `diff_test.Server.Name: "a" != "b"` + "\n"
+	if got != want {
+		t.Errorf("Each(IgnoreFuncs) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, []func(){nil, h2}, []func(){h2, nil}, diff.IgnoreFuncs())
+	if got != "" {
+		t.Errorf("Each(funcs, IgnoreFuncs) = %q, want empty", got)
+	}
+}
+
+func h2() {}
+
+func TestNoiseFloor(t *testing.T) {
+	type T struct {
+		F float64
+		I int
+		U uint8
+		C complex128
+	}
+	cases := []struct {
+		a, b T
+		want string
+	}{
+		{T{1e-9, 0, 0, 0}, T{-3e-9, 0, 0, 0}, ""},
+		{T{1e-9, 0, 0, 0}, T{0.5, 0, 0, 0}, "diff_test.T.F: 1e-09 != 0.5\n"},
+		{T{0, -0, 0, 0}, T{0, 0, 0, 1e-3i}, ""},
+		{T{0, 5, 0, 0}, T{0, 0, 0, 0}, "diff_test.T.I: 5 != 0\n"},
+		{T{math.NaN(), 0, 0, 0}, T{0, 0, 0, 0}, "diff_test.T.F: NaN != 0\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.NoiseFloor(0.01))
+		if got != tt.want {
+			t.Errorf("Each(%v, %v, NoiseFloor) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPickyExceptTime(t *testing.T) {
+	type T struct {
+		Created, Updated, Deleted time.Time
+	}
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := T{t0, t0, t0}
+	b := T{t0.In(time.FixedZone("X", 3600)), t0.Add(time.Second), t0}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.PickyExceptTime)
+	want := "diff_test.T:\n" +
+		"a.Updated(transformed):\n" +
+		tab + "time.Time(2020-01-01T00:00:00Z)\n" +
+		"b.Updated(transformed):\n" +
+		tab + "time.Time(2020-01-01T00:00:01Z)\n" +
+		"diff_test.T:\n" +
+		"a.Updated(original):\n" +
+		tab + "time.Time(2020-01-01T00:00:00Z)\n" +
+		"b.Updated(original):\n" +
+		tab + "time.Time(2020-01-01T00:00:01Z)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.Picky)
+	if !strings.Contains(got, ".Created") {
+		t.Errorf("Picky did not report the zone change:\n%s", got)
+	}
+
+	// Only times show their originals.
+	type U struct {
+		Created time.Time
+		Names   []string
+	}
+	sorted := diff.Transform(func(s []string) any {
+		s = append([]string(nil), s...)
+		sort.Strings(s)
+		return s
+	})
+	got = ""
+	diff.Each(gotp.Printf,
+		U{t0, []string{"b", "a"}},
+		U{t0.Add(time.Second), []string{"c", "a"}},
+		diff.PickyExceptTime, sorted)
+	if !strings.Contains(got, "a.Created(original):") {
+		t.Errorf("diff does not show original times:\n%s", got)
+	}
+	if strings.Contains(got, "Names(original)") {
+		t.Errorf("diff shows original of other transformed type:\n%s", got)
+	}
+}
+
+func TestEmitStable(t *testing.T) {
+	type T struct {
+		C chan int
+		S string
+	}
+	a := T{make(chan int), "a\n\tb\nc"}
+	b := T{make(chan int), "a\n    b\nc"}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitStable)
+	want := "diff_test.T.C: (chan int)(...) != (chan int)(...)\n" +
+		"diff_test.T.S: \n" +
+		"--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" a\n" +
+		"-\tb\n" +
+		"+    b\n" +
+		" c\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.EmitStable, diff.EmitAuto)
+	if strings.Contains(got, "(...)") {
+		t.Errorf("EmitStable then EmitAuto: diff = %q, want addresses", got)
+	}
+}
+
+func TestEmitStableRepeat(t *testing.T) {
+	type T struct{ P *int }
+	a := map[string]T{}
+	b := map[string]T{}
+	for i := 0; i < 50; i++ {
+		k := strconv.Itoa(i)
+		a[k] = T{new(int)}
+		b[k] = T{nil}
+	}
+	var first string
+	for i := 0; i < 10; i++ {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, diff.EmitStable)
+		if i == 0 {
+			first = got
+		} else if got != first {
+			t.Fatalf("run %d: diff = %q, want %q", i, got, first)
+		}
+	}
+	if strings.Count(first, "\n") != 50 {
+		t.Errorf("diff = %q, want 50 lines", first)
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	e1 := errors.New("one")
+	e2 := errors.New("two")
+	e3 := errors.New("three")
+	type T struct{ Err error }
+	cases := []struct {
+		opt  diff.Option
+		a, b error
+		want string
+	}{
+		{diff.MultiError(), errors.Join(e1, e2), errors.Join(e2, e1), ""},
+		{diff.MultiError(), errors.Join(e1, e2), errors.Join(e2, e3),
+			"diff_test.T.Err: (removed) \"one\"\n" +
+				"diff_test.T.Err: (added) \"three\"\n"},
+		{diff.MultiErrorOrdered(), errors.Join(e1, e2), errors.Join(e1, e2), ""},
+		{diff.MultiErrorOrdered(), errors.Join(e1, e2), errors.Join(e1, e3),
+			"diff_test.T.Err[1]: \"two\" != \"three\"\n"},
+		{diff.MultiErrorOrdered(), errors.Join(e1, e2), errors.Join(e1, e2, e3),
+			"diff_test.T.Err[2]: (added) \"three\"\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, T{tt.a}, T{tt.b}, tt.opt)
+		if got != tt.want {
+			t.Errorf("Each(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	columns := []string{"id", "name", "age"}
+	a := [][]any{
+		{1, "ann", 30},
+		{2, "bob", 40},
+		{3, "cy", 50},
+	}
+	b := [][]any{
+		{1, "ann", 31},
+		{3, "cy", 50},
+		{4, "dee", 60},
+	}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.Table(columns), "[][]any[0].age: int(30) != int(31)\n" +
+			"[][]any[1]: (removed) {id: int(2), name: \"bob\", age: int(40)}\n" +
+			"[][]any[3]: (added) {id: int(4), name: \"dee\", age: int(60)}\n"},
+		{diff.OptionList(diff.Table(columns), diff.TableKey("id")), "[][]any[0].age: int(30) != int(31)\n" +
+			"[][]any[1]: (removed) {id: int(2), name: \"bob\", age: int(40)}\n" +
+			"[][]any[3]: (added) {id: int(4), name: \"dee\", age: int(60)}\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, a, b, tt.opt)
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+
+	// With keys, a changed key column doesn't
+	// pair up rows that happen to line up.
+	var got string
+	gotp := (*stringPrinter)(&got)
+	c := [][]any{{5, "ann", 30}}
+	diff.Each(gotp.Printf, a[:1], c, diff.Table(columns), diff.TableKey("id"))
+	want := "[][]any[0]: (removed) {id: int(1), name: \"ann\", age: int(30)}\n" +
+		"[][]any[1]: (added) {id: int(5), name: \"ann\", age: int(30)}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestEmitUnifiedFull(t *testing.T) {
+	type T struct {
+		A, B, C, D, E int
+		S             []string
+	}
+	a := T{A: 1, S: []string{"x", "y"}}
+	b := T{A: 2, S: []string{"x", "z"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitUnifiedFull)
+	want := "diff_test.T:\n" +
+		"--- a\n" +
+		"+++ b\n" +
+		"@@ -1,11 +1,11 @@\n" +
+		" " + tab + "diff_test.T{\n" +
+		"-" + tab + tab + "A: 1,\n" +
+		"+" + tab + tab + "A: 2,\n" +
+		" " + tab + tab + "B: 0,\n" +
+		" " + tab + tab + "C: 0,\n" +
+		" " + tab + tab + "D: 0,\n" +
+		" " + tab + tab + "E: 0,\n" +
+		" " + tab + tab + "S: {\n" +
+		" " + tab + tab + tab + "\"x\",\n" +
+		"-" + tab + tab + tab + "\"y\",\n" +
+		"+" + tab + tab + tab + "\"z\",\n" +
+		" " + tab + tab + "},\n" +
+		" " + tab + "}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, a, diff.EmitUnifiedFull)
+	if got != "" {
+		t.Errorf("diff = %q, want no diff", got)
+	}
+}
+
+func TestEmitUnifiedFullInTest(t *testing.T) {
+	var got string
+	sink := func(format string, arg ...any) {
+		got += fmt.Sprintf(format, arg...)
+	}
+	diff.Test(t, sink, any(1), any(2), diff.EmitUnifiedFull)
+	want := "any:\n" +
+		"--- got\n" +
+		"+++ want\n" +
+		"@@ -0 +0 @@\n" +
+		"-" + tab + "int(1)\n" +
+		"+" + tab + "int(2)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestTimeFormatLayout(t *testing.T) {
+	const layout = "2006-01-02 15:04:05"
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Second)
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, t0, t1, diff.TimeFormat(layout))
+	want := "time.Time(transformed): 2024-01-02 03:04:05 != 2024-01-02 03:04:06 (1s)\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, []time.Time{t0}, []time.Time{t0, t1}, diff.TimeFormat(layout))
+	want = "[]time.Time[1]: (added) 2024-01-02 03:04:06\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+type grpcCode uint32
+
+func (c grpcCode) String() string {
+	switch c {
+	case 5:
+		return "NotFound"
+	case 13:
+		return "Internal"
+	}
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+type grpcStatus struct {
+	code    grpcCode
+	msg     string
+	details []any
+}
+
+func (s *grpcStatus) Code() grpcCode  { return s.code }
+func (s *grpcStatus) Message() string { return s.msg }
+func (s *grpcStatus) Details() []any  { return s.details }
+
+type grpcError struct {
+	s     *grpcStatus
+	trace []string // internal, not compared
+}
+
+func (e *grpcError) Error() string           { return e.s.msg }
+func (e *grpcError) GRPCStatus() *grpcStatus { return e.s }
+
+func TestGRPCStatus(t *testing.T) {
+	a := &grpcError{&grpcStatus{5, "no such user", []any{"u1"}}, []string{"a"}}
+	b := &grpcError{&grpcStatus{5, "no such user", []any{"u1"}}, []string{"b"}}
+	diff.Test(t, t.Errorf, a, b, diff.GRPCStatus())
+	testUnequal(t, a, b)
+
+	c := &grpcError{&grpcStatus{13, "oops", []any{"u2"}}, nil}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, c, diff.GRPCStatus())
+	want := "code=NotFound != code=Internal\n" +
+		"message=\"no such user\" != message=\"oops\"\n" +
+		"*diff_test.grpcError.GRPCStatus().Details()[0]: \"u1\" != \"u2\"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestOnVisit(t *testing.T) {
+	type T struct {
+		A    int
+		S    []string
+		M    map[string]bool
+		When time.Time
+	}
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := T{A: 1, S: []string{"x", "y"}, M: map[string]bool{"k": true}, When: t0}
+	b := T{A: 2, S: []string{"x", "y"}, M: map[string]bool{"k": true}, When: t0}
+	var got []string
+	visit := func(path string, equal bool) {
+		got = append(got, fmt.Sprintf("%s %v", path, equal))
+	}
+	var diffs string
+	gotp := (*stringPrinter)(&diffs)
+	diff.Each(gotp.Printf, a, b, diff.OnVisit(visit))
+	want := []string{
+		"diff_test.T.A false",
+		"diff_test.T.S[0] true",
+		"diff_test.T.S[1] true",
+		`diff_test.T.M["k"] true`,
+		"diff_test.T.When(transformed) true",
+	}
+	diff.Test(t, t.Errorf, got, want)
+
+	var plain string
+	gotp = (*stringPrinter)(&plain)
+	diff.Each(gotp.Printf, a, b)
+	if diffs != plain {
+		t.Errorf("diff with OnVisit = %q, want %q", diffs, plain)
+	}
+}
+
+func TestPtrSliceSemantics(t *testing.T) {
+	type T struct{ A, B int }
+	a := []*T{{1, 1}, nil, {2, 2}, {3, 3}}
+	b := []*T{{1, 1}, {4, 4}, nil, {3, 5}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a[:2], b[:2], diff.PtrSliceSemantics())
+	diff.Each(gotp.Printf, a[2:], b[2:], diff.PtrSliceSemantics())
+	want := "[]*diff_test.T[1]: (nil->set) {\n" +
+		tab + "A: 4,\n" +
+		tab + "B: 4,\n" +
+		"}\n" +
+		"[]*diff_test.T[0]: (set->nil) {\n" +
+		tab + "A: 2,\n" +
+		tab + "B: 2,\n" +
+		"}\n" +
+		"[]*diff_test.T[1].B: 3 != 5\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	c := []*T{{1, 1}, nil}
+	d := []*T{{1, 1}, nil}
+	diff.Test(t, t.Errorf, c, d, diff.PtrSliceSemantics())
+}
+
+func TestInterfaceTypeChanges(t *testing.T) {
+	type T struct{ F, G any }
+	a := T{F: 5, G: []int{1}}
+	b := T{F: "x", G: []int{2}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.InterfaceTypeChanges())
+	want := "diff_test.T.F: type int != string (values: 5, \"x\")\n" +
+		"diff_test.T.G[0]: 1 != 2\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, T{F: 5}, T{}, diff.InterfaceTypeChanges())
+	want = "diff_test.T.F: int(5) != nil\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestChannelShape(t *testing.T) {
+	type T struct {
+		C chan int
+		D any
+	}
+	c0 := make(chan int)
+	c8 := make(chan int, 8)
+	c8 <- 1
+	diff.Test(t, t.Errorf, T{C: make(chan int, 2)}, T{C: make(chan int, 2)}, diff.ChannelShape())
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a := T{C: c0, D: make(chan int)}
+	b := T{C: c8, D: (chan<- int)(make(chan int))}
+	diff.Each(gotp.Printf, a, b, diff.ChannelShape())
+	want := "diff_test.T.C: cap 0 != 8\n" +
+		"diff_test.T.C: len 0 != 1\n" +
+		"diff_test.T.D: dir chan != chan<-\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+	if n := len(c8); n != 1 {
+		t.Errorf("len(c8) = %d, want 1", n)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, T{}, T{C: c0}, diff.ChannelShape())
+	if want := "diff_test.T.C: nil != non-nil\n"; got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
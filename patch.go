@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"reflect"
+
+	"kr.dev/diff/internal/diffseq"
+)
+
+// An Assign is a change that, applied to the first value
+// given to Patch, makes part of it equal to the second.
+type Assign struct {
+	// Path holds the elements of the path to the changed
+	// value, such as ".Name", "[2]", or `["key"]`.
+	Path []string
+
+	// Value is the new value at Path,
+	// or nil if Removed is true.
+	Value any
+
+	// Removed reports whether the value at Path is
+	// present only in the first value, so it should be
+	// deleted rather than assigned. For a slice element,
+	// the later elements move down to fill its place.
+	Removed bool
+
+	// Inserted reports whether Value is a slice element
+	// present only in the second value, so it should be
+	// inserted at Path, moving the later elements up,
+	// rather than assigned.
+	Inserted bool
+}
+
+// Patch compares values a and b and returns a list of
+// assignments that describes how to change a into b.
+// Applying the assignments to a in order makes it equal to b.
+// The index of each slice element in Path refers to the slice
+// as changed by the assignments before it.
+//
+// Strings are not compared piecewise; a differing string
+// is reported as a single assignment of the whole string.
+// Likewise, a value compared through a transform or in
+// some other form, such as a time.Time with TimeEqual,
+// is reported as a single assignment of its value in b,
+// with no pseudo-element such as "(transformed)" in Path.
+// Elements of arrays are assigned by index.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+// Options that control output, such as EmitFull and
+// GroupByPrefix, have no effect. Options that change how
+// slice elements are matched, such as KeyedSlice and
+// DetectMoves, make the result unsuitable for applying.
+func Patch(a, b any, opt ...Option) []Assign {
+	var as []Assign
+	var c config
+	c.init(func() {}, func(string, ...any) {}, opt...)
+	c.level = full
+	c.groupByPrefix = false
+	c.summarize = false
+	c.patch = &as
+	each(a, b, &c)
+	return as
+}
+
+// assign returns an Assign for the current difference.
+func (e *emitter) assign() Assign {
+	path, bv := e.path, e.bv
+	if e.hasOrig {
+		path, bv = path[:e.origLen], e.origB
+	}
+	a := Assign{Path: append([]string(nil), path...), Inserted: e.insert}
+	if e.av.IsValid() && !e.bv.IsValid() && !e.hasOrig {
+		a.Removed = true
+	} else if v, ok := usable(bv); ok && v.IsValid() {
+		a.Value = v.Interface()
+	}
+	return a
+}
+
+// patchSeqDiff compares sequences as and bs for Patch,
+// giving each removed or inserted element its index in
+// the sequence as changed by the elements before it.
+func patchSeqDiff(e *emitter, as, bs reflect.Value) {
+	e.config.helper()
+	t := as.Type()
+	if as.Kind() == reflect.Array {
+		for i := 0; i < as.Len(); i++ {
+			walk(e.index(t, i), as.Index(i), bs.Index(i), true, false)
+		}
+		return
+	}
+	eq := func(a, b reflect.Value, ai, bi int) bool {
+		return equal(a.Index(ai), b.Index(bi), &e.config, true)
+	}
+	shift := 0 // net number of elements inserted so far
+	for _, ed := range diffseq.Diff(as, bs, eq) {
+		n := min(ed.A1-ed.A0, ed.B1-ed.B0)
+		for i := 0; i < n; i++ {
+			walk(e.index(t, ed.A0+i+shift), as.Index(ed.A0+i), bs.Index(ed.B0+i), true, false)
+		}
+		for i := ed.A0 + n; i < ed.A1; i++ {
+			ee := e.index(t, i+shift)
+			ee.set(as.Index(i), reflect.Value{})
+			ee.emitf("%s", e.config.removedLabel)
+			shift--
+		}
+		for j := ed.B0 + n; j < ed.B1; j++ {
+			ee := e.index(t, ed.A1+shift)
+			ee.set(reflect.Value{}, bs.Index(j))
+			ee.insert = true
+			ee.emitf("%s", e.config.addedLabel)
+			shift++
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package diff_test
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+)
+
+func TestPatch(t *testing.T) {
+	type T struct {
+		Name string
+		Tags []string
+		M    map[string]int
+		n    int
+	}
+	a := T{
+		Name: "a long name that would be diffed in pieces",
+		Tags: []string{"x", "y"},
+		M:    map[string]int{"k": 1, "old": 2},
+		n:    1,
+	}
+	b := T{
+		Name: "a long name that would be compared in pieces",
+		Tags: []string{"x"},
+		M:    map[string]int{"k": 3, "new": 4},
+		n:    2,
+	}
+	got := diff.Patch(a, b)
+	want := []diff.Assign{
+		{Path: []string{".Name"}, Value: "a long name that would be compared in pieces"},
+		{Path: []string{".Tags", "[1]"}, Removed: true},
+		{Path: []string{".M", `["k"]`}, Value: 3},
+		{Path: []string{".M", `["new"]`}, Value: 4},
+		{Path: []string{".M", `["old"]`}, Removed: true},
+		{Path: []string{".n"}, Value: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Patch() = %#v, want %#v", got, want)
+	}
+
+	if got := diff.Patch(a, a); got != nil {
+		t.Errorf("Patch(a, a) = %#v, want nil", got)
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	type Item struct {
+		ID   int
+		Tags []string
+	}
+	type T struct {
+		Name    string
+		Created time.Time
+		Items   []Item
+		Nums    []int
+		M       map[string][]int
+		A       [3]int
+	}
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := T{
+		Name:    "a",
+		Created: t0,
+		Items:   []Item{{1, []string{"x"}}, {2, nil}, {3, []string{"y", "z"}}},
+		Nums:    []int{1, 2, 3, 4, 5, 6},
+		M:       map[string][]int{"k": {1, 2}, "old": {3}},
+		A:       [3]int{1, 2, 3},
+	}
+	b := T{
+		Name:    "b",
+		Created: t0.Add(time.Hour).In(time.FixedZone("X", 3600)),
+		Items:   []Item{{0, nil}, {1, []string{"x", "w"}}, {3, []string{"z"}}, {4, nil}},
+		Nums:    []int{0, 2, 7, 8, 4, 6, 9},
+		M:       map[string][]int{"k": {2}, "new": {4}},
+		A:       [3]int{2, 3, 4},
+	}
+	patch := diff.Patch(a, b)
+	for _, as := range patch {
+		for _, p := range as.Path {
+			if strings.HasPrefix(p, "(") {
+				t.Errorf("Patch path %q has pseudo-element", as.Path)
+			}
+		}
+	}
+	got := a
+	got.Items = append([]Item(nil), a.Items...)
+	v := reflect.ValueOf(&got).Elem()
+	for _, as := range patch {
+		v.Set(applyAssign(v, as.Path, as))
+	}
+	diff.Test(t, t.Errorf, got, b, diff.Picky)
+	if a.Name != "a" || len(a.Nums) != 6 || a.Nums[0] != 1 {
+		t.Errorf("applying patch modified a: %+v", a)
+	}
+}
+
+// applyAssign returns a copy of v with as applied at path.
+func applyAssign(v reflect.Value, path []string, as diff.Assign) reflect.Value {
+	if len(path) == 0 {
+		if as.Value == nil {
+			return reflect.Zero(v.Type())
+		}
+		return reflect.ValueOf(as.Value)
+	}
+	el, last := path[0], len(path) == 1
+	switch {
+	case strings.HasPrefix(el, "."):
+		nv := reflect.New(v.Type()).Elem()
+		nv.Set(v)
+		f := nv.FieldByName(el[1:])
+		f.Set(applyAssign(f, path[1:], as))
+		return nv
+	case strings.HasPrefix(el, `["`):
+		k, err := strconv.Unquote(el[1 : len(el)-1])
+		if err != nil {
+			panic(err)
+		}
+		kv := reflect.ValueOf(k)
+		nv := reflect.MakeMap(v.Type())
+		iter := v.MapRange()
+		for iter.Next() {
+			nv.SetMapIndex(iter.Key(), iter.Value())
+		}
+		if last && as.Removed {
+			nv.SetMapIndex(kv, reflect.Value{})
+			return nv
+		}
+		cur := nv.MapIndex(kv)
+		if !cur.IsValid() {
+			cur = reflect.Zero(v.Type().Elem())
+		}
+		nv.SetMapIndex(kv, applyAssign(cur, path[1:], as))
+		return nv
+	default:
+		i, err := strconv.Atoi(el[1 : len(el)-1])
+		if err != nil {
+			panic(err)
+		}
+		if v.Kind() == reflect.Array {
+			nv := reflect.New(v.Type()).Elem()
+			nv.Set(v)
+			nv.Index(i).Set(applyAssign(nv.Index(i), path[1:], as))
+			return nv
+		}
+		var elems []reflect.Value
+		for j := 0; j < v.Len(); j++ {
+			elems = append(elems, v.Index(j))
+		}
+		switch {
+		case last && as.Removed:
+			elems = append(elems[:i], elems[i+1:]...)
+		case last && as.Inserted:
+			elems = append(elems[:i], append([]reflect.Value{reflect.ValueOf(as.Value)}, elems[i:]...)...)
+		default:
+			elems[i] = applyAssign(elems[i], path[1:], as)
+		}
+		nv := reflect.MakeSlice(v.Type(), 0, len(elems))
+		return reflect.Append(nv, elems...)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build go1.23
+
+package diff
+
+import "iter"
+
+// Seq compares the sequences of values yielded by a and b,
+// calling f for each difference it finds, in the same way
+// as Each compares two slices.
+//
+// Seq collects the values from each iterator into a slice
+// before comparing them. To guard against infinite
+// iterators, it stops after the number of values set by
+// SeqLimit, and compares only the values collected so far.
+// If it stops early, it first calls f with a line noting
+// that further values were not compared, before reporting
+// any differences. That line is not a difference; it isn't
+// counted by Summarize.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func Seq[T any](f func(format string, arg ...any) (int, error), a, b iter.Seq[T], opt ...Option) {
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	var c config
+	c.init(func() {}, fdis, opt...)
+	c.noRootType = true
+	as, amore := collect(a, c.seqLimit)
+	bs, bmore := collect(b, c.seqLimit)
+	seqMore(&c, amore || bmore)
+	each(as, bs, &c)
+}
+
+// Seq2 is like Seq, but compares sequences of pairs of
+// values. Each pair is compared as a struct with fields
+// Key and Value.
+func Seq2[K, V any](f func(format string, arg ...any) (int, error), a, b iter.Seq2[K, V], opt ...Option) {
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	var c config
+	c.init(func() {}, fdis, opt...)
+	c.noRootType = true
+	as, amore := collect2(a, c.seqLimit)
+	bs, bmore := collect2(b, c.seqLimit)
+	seqMore(&c, amore || bmore)
+	each(as, bs, &c)
+}
+
+// A seqPair is a pair of values yielded by an iter.Seq2.
+type seqPair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// seqMore notes that values beyond the limit
+// were not compared, if more is true.
+func seqMore(c *config, more bool) {
+	if more {
+		c.note("(+ more values after the first %d, not compared)\n", c.seqLimit)
+	}
+}
+
+// collect returns up to n values yielded by seq,
+// and whether seq yielded more.
+func collect[T any](seq iter.Seq[T], n int) (s []T, more bool) {
+	for v := range seq {
+		if len(s) >= n {
+			return s, true
+		}
+		s = append(s, v)
+	}
+	return s, false
+}
+
+// collect2 returns up to n pairs of values yielded by seq,
+// and whether seq yielded more.
+func collect2[K, V any](seq iter.Seq2[K, V], n int) (s []seqPair[K, V], more bool) {
+	for k, v := range seq {
+		if len(s) >= n {
+			return s, true
+		}
+		s = append(s, seqPair[K, V]{k, v})
+	}
+	return s, false
+}
@@ -0,0 +1,65 @@
+//go:build go1.23
+
+package diff_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestSeq(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Seq(gotp.Printf, slices.Values([]int{1, 2, 3}), slices.Values([]int{1, 5, 3, 4}))
+	want := "[1]: 2 != 5\n" +
+		"[3]: (added) 4\n"
+	if got != want {
+		t.Errorf("Seq() = %q, want %q", got, want)
+	}
+
+	got = ""
+	naturals := func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+	diff.Seq(gotp.Printf, naturals, naturals, diff.SeqLimit(100))
+	want = "(+ more values after the first 100, not compared)\n"
+	if got != want {
+		t.Errorf("Seq(naturals) = %q, want %q", got, want)
+	}
+
+	got = ""
+	evens := func(yield func(int) bool) {
+		for i := 0; yield(2 * i); i++ {
+		}
+	}
+	diff.Seq(gotp.Printf, naturals, evens, diff.SeqLimit(3), diff.Summarize())
+	want = "(+ more values after the first 3, not compared)\n" +
+		"[1]: (removed) 1\n" +
+		"[3]: (added) 4\n" +
+		"# 2 differences\n"
+	if got != want {
+		t.Errorf("Seq(naturals, evens) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Seq(gotp.Printf, slices.Values([]int{1, 2}), slices.Values([]int{1, 2}), diff.SeqLimit(2))
+	if got != "" {
+		t.Errorf("Seq() = %q, want empty", got)
+	}
+}
+
+func TestSeq2(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a := maps.All(map[string]int{"x": 1})
+	b := maps.All(map[string]int{"x": 2})
+	diff.Seq2(gotp.Printf, a, b)
+	want := "[0].Value: 1 != 2\n"
+	if got != want {
+		t.Errorf("Seq2() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A shapeLeaf is a placeholder in a shape given to Shape.
+// It matches any value if t is nil, and otherwise
+// any value of type t.
+type shapeLeaf struct{ t reflect.Type }
+
+var shapeLeafType = reflect.TypeOf(shapeLeaf{})
+
+// Any is a placeholder, for use in a shape given to Shape,
+// that matches any value, including nil.
+var Any any = shapeLeaf{}
+
+// OfType returns a placeholder, for use in a shape given
+// to Shape, that matches any value of type T.
+// If T is an interface type, it matches any value
+// that implements T.
+func OfType[T any]() any {
+	return shapeLeaf{reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+// Shape compares got with shape, a value in which some parts
+// are replaced by the placeholders Any and OfType, and returns
+// a description of each difference, formatted as by Each.
+// It returns nil if got matches shape.
+//
+// Placeholders can appear wherever the shape holds an
+// interface value, such as in a map[string]any or []any.
+// At a placeholder, only the presence and type of the
+// corresponding value in got are checked. Elsewhere, values
+// are compared as usual, except that map keys absent from
+// the shape are ignored, so the shape need only list the
+// entries it cares about. Entries in the shape that are
+// absent from got are reported as missing.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func Shape(got, shape any, opt ...Option) []string {
+	var diffs []string
+	var c config
+	c.init(func() {}, func(format string, arg ...any) {
+		diffs = append(diffs, strings.TrimSuffix(fmt.Sprintf(format, arg...), "\n"))
+	}, opt...)
+	c.aLabel = "got"
+	c.bLabel = "shape"
+	c.shape = true
+	each(got, shape, &c)
+	return diffs
+}
+
+// shapeLeafDiff checks av against placeholder bv.
+func shapeLeafDiff(e *emitter, av, bv reflect.Value) {
+	e.config.helper()
+	t := bv.Interface().(shapeLeaf).t
+	if t == nil {
+		return
+	}
+	if !av.IsValid() {
+		e.emitf("nil, want %s", e.typeName(t))
+		return
+	}
+	if av.Type() == t || t.Kind() == reflect.Interface && av.Type().Implements(t) {
+		return
+	}
+	e.emitf("%v, want %s", e.short(av, true), e.typeName(t))
+}
@@ -0,0 +1,55 @@
+package diff_test
+
+import (
+	"fmt"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestShape(t *testing.T) {
+	got := map[string]any{
+		"id":    42,
+		"name":  "ann",
+		"tags":  []any{"a", "b"},
+		"extra": true,
+		"err":   fmt.Errorf("x"),
+	}
+	shape := map[string]any{
+		"id":   diff.OfType[int](),
+		"name": diff.Any,
+		"tags": []any{diff.OfType[string](), "b"},
+		"err":  diff.OfType[error](),
+	}
+	if d := diff.Shape(got, shape); d != nil {
+		t.Errorf("Shape = %q, want nil", d)
+	}
+
+	shape = map[string]any{
+		"id":      diff.OfType[string](),
+		"name":    diff.Any,
+		"missing": diff.Any,
+		"tags":    []any{"a", "c"},
+	}
+	want := []string{
+		`map[string]any["id"]: int(42), want string`,
+		`map[string]any["missing"]: (missing)`,
+		`map[string]any["tags"][1]: "b" != "c"`,
+	}
+	diff.Test(t, t.Errorf, diff.Shape(got, shape), want)
+}
+
+func TestShapeOneof(t *testing.T) {
+	got := map[string]any{"id": 42, "name": "ann"}
+	shape := map[string]any{
+		"id":   diff.OfType[int](),
+		"name": diff.Any,
+	}
+	if d := diff.Shape(got, shape, diff.Oneof()); d != nil {
+		t.Errorf("Shape = %q, want nil", d)
+	}
+
+	shape["id"] = diff.OfType[string]()
+	want := []string{`map[string]any["id"]: int(42), want string`}
+	diff.Test(t, t.Errorf, diff.Shape(got, shape, diff.Oneof()), want)
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package diff
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler sets the output for Log to logger, which
+// receives each difference as a log record at the given
+// level. The record's message describes the difference,
+// and its attributes "path", "a", and "b" hold the path
+// to the difference and the short representations of the
+// two values there, as used by EmitAuto. An attribute is
+// omitted if its value is absent, such as "a" for an
+// added element.
+// It has no effect on Each or Test.
+func SlogHandler(logger *slog.Logger, level slog.Level) Option {
+	return Option{func(c *config) {
+		c.logRecord = func(path, msg, a, b string) {
+			attrs := []slog.Attr{slog.String("path", path)}
+			if a != "" {
+				attrs = append(attrs, slog.String("a", a))
+			}
+			if b != "" {
+				attrs = append(attrs, slog.String("b", b))
+			}
+			logger.LogAttrs(context.Background(), level, msg, attrs...)
+		}
+	}}
+}
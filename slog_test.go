@@ -0,0 +1,74 @@
+//go:build go1.21
+
+package diff_test
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	noTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: noTime})
+	logger := slog.New(h)
+
+	type T struct {
+		A int
+		S []string
+	}
+	a := T{A: 1, S: []string{"x"}}
+	b := T{A: 2, S: []string{"x", "y"}}
+	diff.Log(a, b, diff.SlogHandler(logger, slog.LevelWarn))
+	want := `level=WARN msg="1 != 2" path=diff_test.T.A a=1 b=2` + "\n" +
+		`level=WARN msg="(added) \"y\"" path=diff_test.T.S[1] b="\"y\""` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("bad log")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	// Each is unaffected.
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.SlogHandler(logger, slog.LevelWarn))
+	if want := "diff_test.T.A: 1 != 2\ndiff_test.T.S[1]: (added) \"y\"\n"; got != want {
+		t.Errorf("Each = %q, want %q", got, want)
+	}
+}
+
+func TestSlogHandlerOptions(t *testing.T) {
+	var buf, out bytes.Buffer
+	h := slog.NewTextHandler(&buf, nil)
+	logger := slog.New(h)
+	a := []int{1, 2, 3}
+	b := []int{4, 5, 6}
+
+	diff.Log(a, b, diff.SlogHandler(logger, slog.LevelInfo), diff.FailFast())
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Errorf("FailFast: logged %d records, want 1:\n%s", n, buf.String())
+	}
+
+	buf.Reset()
+	diff.Log(a, b,
+		diff.SlogHandler(logger, slog.LevelInfo),
+		diff.Logger(log.New(&out, "", 0)),
+		diff.Summarize(),
+	)
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("Summarize: logged %d records, want 3:\n%s", n, buf.String())
+	}
+	if got, want := out.String(), "# 3 differences\n"; got != want {
+		t.Errorf("Summarize: output = %q, want %q", got, want)
+	}
+}
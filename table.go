@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"kr.dev/diff/internal/diffseq"
+)
+
+// tableDiff compares as and bs, slices of rows, as tables
+// with the columns named in the config. Rows are matched
+// by the key column, if there is one, or else by position,
+// allowing for inserted and deleted rows.
+// Matched rows are compared cell by cell, with each cell's
+// path naming its column. Rows with no match are reported
+// as removed or added.
+func tableDiff(e *emitter, as, bs reflect.Value) {
+	e.config.helper()
+	t := as.Type()
+	lim := e.limiter()
+	defer lim.emitMore(e, as, bs)
+	if key := e.tableKeyIndex(); key >= 0 {
+		rowKey := func(row reflect.Value) any {
+			if key >= row.Len() {
+				return nil
+			}
+			k := usableInterface(row.Index(key))
+			if k != nil && !reflect.TypeOf(k).Comparable() {
+				return fmt.Sprintf("%#v", k)
+			}
+			return k
+		}
+		bIndex := map[any][]int{}
+		for j := 0; j < bs.Len(); j++ {
+			k := rowKey(bs.Index(j))
+			bIndex[k] = append(bIndex[k], j)
+		}
+		matched := make([]bool, bs.Len())
+		for i := 0; i < as.Len(); i++ {
+			k := rowKey(as.Index(i))
+			if js := bIndex[k]; len(js) > 0 {
+				bIndex[k] = js[1:]
+				matched[js[0]] = true
+				if lim.limit > 0 && equal(as.Index(i), bs.Index(js[0]), &e.config, true) || !lim.allow() {
+					continue
+				}
+				rowDiff(e.index(t, i), as.Index(i), bs.Index(js[0]))
+				continue
+			}
+			if !lim.allow() {
+				continue
+			}
+			emitRow(e.index(t, i), e.config.removedLabel, as.Index(i), true)
+		}
+		for j := 0; j < bs.Len(); j++ {
+			if !matched[j] && lim.allow() {
+				emitRow(e.index(t, as.Len()), e.config.addedLabel, bs.Index(j), false)
+			}
+		}
+		return
+	}
+
+	eq := func(a, b reflect.Value, ai, bi int) bool {
+		return equal(a.Index(ai), b.Index(bi), &e.config, true)
+	}
+	for _, ed := range diffseq.Diff(as, bs, eq) {
+		n := min(ed.A1-ed.A0, ed.B1-ed.B0)
+		for i := 0; i < n; i++ {
+			if lim.allow() {
+				rowDiff(e.index(t, ed.A0+i), as.Index(ed.A0+i), bs.Index(ed.B0+i))
+			}
+		}
+		for i := ed.A0 + n; i < ed.A1; i++ {
+			if lim.allow() {
+				emitRow(e.index(t, i), e.config.removedLabel, as.Index(i), true)
+			}
+		}
+		for j := ed.B0 + n; j < ed.B1; j++ {
+			if lim.allow() {
+				emitRow(e.index(t, ed.A1), e.config.addedLabel, bs.Index(j), false)
+			}
+		}
+	}
+}
+
+// tableKeyIndex returns the index of the key column,
+// or -1 if there is none.
+func (e *emitter) tableKeyIndex() int {
+	if e.config.tableKey == "" {
+		return -1
+	}
+	for i, name := range e.config.tableColumns {
+		if name == e.config.tableKey {
+			return i
+		}
+	}
+	return -1
+}
+
+// usableInterface returns the value held by v,
+// or nil if it can't be used.
+func usableInterface(v reflect.Value) any {
+	v, ok := usable(v)
+	if !ok || !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// rowDiff compares rows a and b cell by cell.
+func rowDiff(e *emitter, a, b reflect.Value) {
+	e.config.helper()
+	t := a.Type()
+	n := max(a.Len(), b.Len())
+	for i := 0; i < n; i++ {
+		var ac, bc reflect.Value
+		if i < a.Len() {
+			ac = a.Index(i)
+		}
+		if i < b.Len() {
+			bc = b.Index(i)
+		}
+		walk(e.column(t, i), ac, bc, true, false)
+	}
+}
+
+// column returns an emitter for column i of a row of type t.
+func (e *emitter) column(t reflect.Type, i int) *emitter {
+	if i < len(e.config.tableColumns) {
+		return e.subf(t, ".%s", e.config.tableColumns[i])
+	}
+	return e.index(t, i)
+}
+
+// emitRow emits a row present on only one side,
+// as a list of its cells labeled by column name.
+func emitRow(e *emitter, label string, row reflect.Value, removed bool) {
+	e.config.helper()
+	if removed {
+		e.set(row, reflect.Value{})
+	} else {
+		e.set(reflect.Value{}, row)
+	}
+	var buf strings.Builder
+	buf.WriteString("{")
+	for i := 0; i < row.Len(); i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if i < len(e.config.tableColumns) {
+			buf.WriteString(e.config.tableColumns[i])
+			buf.WriteString(": ")
+		}
+		fmt.Fprintf(&buf, "%v", e.short(row.Index(i), false))
+	}
+	buf.WriteString("}")
+	e.emitf("%s %s", label, buf.String())
+}
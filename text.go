@@ -1,6 +1,10 @@
 package diff
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"reflect"
@@ -11,12 +15,14 @@ import (
 	"kr.dev/diff/internal/diffseq"
 )
 
-const nContext = 3
+const (
+	nContext      = 3
+	nContextRunes = 6 // for ContextualStrings
+)
 
 var (
 	identity = strings.NewReplacer()
 	stripWS  = strings.NewReplacer(" ", "", "\t", "")
-	visWS    = strings.NewReplacer(" ", "\u00b7", "\t", " \u2192 ")
 )
 
 func textDiff(e *emitter, t reflect.Type, a, b string) {
@@ -24,19 +30,37 @@ func textDiff(e *emitter, t reflect.Type, a, b string) {
 
 	// TODO(kr): check for whitespace-only changes, use special format
 
-	if e.config.level == full {
+	if e.config.level == full || e.config.level == goLiteral {
 		e.emitf("")
 		return
 	}
 
 	// Check for multi-line.
 	if textCheck(a, "\n", 2, 72) && textCheck(b, "\n", 2, 72) {
-		e.emitf("\n%s", &diffTextFormatter{a, b, e.config.aLabel, e.config.bLabel})
+		e.emitf("\n%s", &diffTextFormatter{
+			a:         a,
+			b:         b,
+			aLabel:    e.config.aLabel,
+			bLabel:    e.config.bLabel,
+			visWS:     e.config.visWS,
+			lineNums:  e.config.textLineNums,
+			intraLine: e.config.intraLine,
+		})
 		return
 	}
 
 	// Check for short strings.
 	if len(a) < 20 && len(b) < 20 || a == "" || b == "" {
+		if e.config.contextual {
+			if pre, suf := commonAffix(a, b); pre > 0 || suf > 0 {
+				e.emitf("%s[%+q != %+q]%s",
+					contextBefore(a[:pre]),
+					a[pre:len(a)-suf], b[pre:len(b)-suf],
+					contextAfter(a[len(a)-suf:]),
+				)
+				return
+			}
+		}
 		e.emitf("%+q != %+q", a, b)
 		return
 	}
@@ -55,12 +79,177 @@ func textDiff(e *emitter, t reflect.Type, a, b string) {
 	textDiffInline(e, t, a, b, as, bs)
 }
 
+// commonAffix returns the lengths in bytes of the longest
+// common prefix and suffix of a and b, at rune boundaries.
+// The prefix and suffix do not overlap.
+func commonAffix(a, b string) (pre, suf int) {
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	for pre > 0 && pre < len(a) && !utf8.RuneStart(a[pre]) {
+		pre--
+	}
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	for suf > 0 && !utf8.RuneStart(a[len(a)-suf]) {
+		suf--
+	}
+	return pre, suf
+}
+
+// contextBefore returns the last few runes of s, escaped,
+// preceded by "…" if any were omitted.
+func contextBefore(s string) string {
+	r := []rune(s)
+	if len(r) > nContextRunes {
+		return "…" + quoteBare(string(r[len(r)-nContextRunes:]))
+	}
+	return quoteBare(s)
+}
+
+// contextAfter returns the first few runes of s, escaped,
+// followed by "…" if any were omitted.
+func contextAfter(s string) string {
+	r := []rune(s)
+	if len(r) > nContextRunes {
+		return quoteBare(string(r[:nContextRunes])) + "…"
+	}
+	return quoteBare(s)
+}
+
+// quoteBare is like %+q without the surrounding quotes.
+func quoteBare(s string) string {
+	q := strconv.QuoteToASCII(s)
+	return q[1 : len(q)-1]
+}
+
+// Text reads a and b in full and writes a unified diff
+// of their lines to w.
+// It writes nothing if the contents of a and b are equal.
+// The line diff needs all of both inputs at once, so Text
+// holds the entire contents of a and b in memory; it does
+// not stream them. It is a convenience for callers that
+// already have readers, not a way to diff inputs too large
+// to fit in memory.
+//
+// Text respects only the options that affect text diffs:
+// WithLabels, Reverse, WhitespaceMarkers, TextLineNumbers,
+// and IntraLineHighlight. Other options are ignored.
+func Text(w io.Writer, a, b io.Reader, opt ...Option) error {
+	as, err := io.ReadAll(a)
+	if err != nil {
+		return err
+	}
+	bs, err := io.ReadAll(b)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(as, bs) {
+		return nil
+	}
+	var c config
+	c.init(func() {}, nil, opt...)
+	if c.reverse {
+		as, bs = bs, as
+		c.aLabel, c.bLabel = c.bLabel, c.aLabel
+	}
+	_, err = fmt.Fprint(w, &diffTextFormatter{
+		a:         string(as),
+		b:         string(bs),
+		aLabel:    c.aLabel,
+		bLabel:    c.bLabel,
+		visWS:     c.visWS,
+		lineNums:  c.textLineNums,
+		intraLine: c.intraLine,
+	})
+	return err
+}
+
+// base64TextDiff compares byte slices a and b after
+// decoding them with decodeBase64Text, as a line diff.
+func base64TextDiff(e *emitter, a, b string) {
+	e.config.helper()
+	if a == b {
+		return
+	}
+	as := strings.TrimSuffix(decodeBase64Text(a), "\n")
+	bs := strings.TrimSuffix(decodeBase64Text(b), "\n")
+	if as == bs {
+		return
+	}
+	if e.config.level == full || e.config.level == goLiteral {
+		e.emitf("")
+		return
+	}
+	e.emitf("\n%s", &diffTextFormatter{
+		a:         as,
+		b:         bs,
+		aLabel:    e.config.aLabel,
+		bLabel:    e.config.bLabel,
+		visWS:     e.config.visWS,
+		lineNums:  e.config.textLineNums,
+		intraLine: e.config.intraLine,
+	})
+}
+
+// decodeBase64Text returns a line-oriented form of s.
+// If s holds PEM blocks, it returns their types and headers
+// with a hex dump of the contents of each block.
+// Otherwise, if s is valid base64, it returns the decoded
+// data if that is UTF-8 text, or a hex dump if not.
+// Otherwise it returns a hex dump of s itself.
+func decodeBase64Text(s string) string {
+	if block, rest := pem.Decode([]byte(s)); block != nil {
+		var buf strings.Builder
+		for block != nil {
+			fmt.Fprintf(&buf, "-----BEGIN %s-----\n", block.Type)
+			for _, k := range sortedNames(block.Headers, nil) {
+				fmt.Fprintf(&buf, "%s: %s\n", k, block.Headers[k])
+			}
+			buf.WriteString(hex.Dump(block.Bytes))
+			fmt.Fprintf(&buf, "-----END %s-----\n", block.Type)
+			block, rest = pem.Decode(rest)
+		}
+		if r := bytes.TrimSpace(rest); len(r) > 0 {
+			buf.WriteString(hex.Dump(r))
+		}
+		return buf.String()
+	}
+	stripped := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	} {
+		if p, err := enc.DecodeString(stripped); err == nil {
+			if utf8.Valid(p) {
+				return string(p)
+			}
+			return hex.Dump(p)
+		}
+	}
+	return hex.Dump([]byte(s))
+}
+
 func textDiffInline(e *emitter, t reflect.Type, a, b string, as, bs []string) {
 	e.config.helper()
 
+	edits := diffseq.DiffSlice(as, bs)
+	if n := e.config.inlineLimit; n > 0 && len(edits) > n {
+		e.emitf("%+q != %+q", a, b)
+		return
+	}
 	acut := accum(as)
 	bcut := accum(bs)
-	for _, ed := range diffseq.DiffSlice(as, bs) {
+	for _, ed := range edits {
 		a0, a1 := acut[ed.A0], acut[ed.A1]
 		b0, b1 := bcut[ed.B0], bcut[ed.B1]
 		ee := e.subf(t, "[%d:%d]", a0, a1)
@@ -68,12 +257,44 @@ func textDiffInline(e *emitter, t reflect.Type, a, b string, as, bs []string) {
 	}
 }
 
+// byteDiff compares binary data a and b byte by byte.
+// It shows each single differing byte in hex and,
+// if it is printable ASCII, as a character.
+func byteDiff(e *emitter, t reflect.Type, a, b string) {
+	e.config.helper()
+	as := splitBytes(a)
+	bs := splitBytes(b)
+	for _, ed := range diffseq.DiffSlice(as, bs) {
+		if ed.A1-ed.A0 == 1 && ed.B1-ed.B0 == 1 {
+			ee := e.subf(t, "[%d]", ed.A0)
+			ee.emitf("%s != %s", byteChar(a[ed.A0]), byteChar(b[ed.B0]))
+			continue
+		}
+		ee := e.subf(t, "[%d:%d]", ed.A0, ed.A1)
+		ee.emitf("%+q != %+q", a[ed.A0:ed.A1], b[ed.B0:ed.B1])
+	}
+}
+
+// byteChar returns c in hex, followed by c quoted
+// as a character if it is printable ASCII.
+func byteChar(c byte) string {
+	if c >= ' ' && c <= '~' {
+		return fmt.Sprintf("%#02x %q", c, c)
+	}
+	return fmt.Sprintf("%#02x", c)
+}
+
 func textCheck(s, sep string, nmin, amax int) bool {
 	n := strings.Count(s, sep) + 1
 	return n >= nmin && len(s)/n <= amax
 }
 
-type diffTextFormatter struct{ a, b, aLabel, bLabel string }
+type diffTextFormatter struct {
+	a, b, aLabel, bLabel string
+	visWS                *strings.Replacer // for whitespace-only changes
+	lineNums             bool              // show a gutter of line numbers
+	intraLine            bool              // mark changed runes in paired lines
+}
 
 func (df *diffTextFormatter) Format(f fmt.State, verb rune) {
 	fmt.Fprintf(f, "--- %s\n", df.aLabel)
@@ -82,10 +303,11 @@ func (df *diffTextFormatter) Format(f fmt.State, verb rune) {
 	bs := strings.Split(df.b, "\n")
 
 	merged := diffseq.DiffSlice(as, bs)
+	width := len(strconv.Itoa(max(len(as), len(bs))))
 
 	for i := 0; i < len(merged); {
 		ed := merged[i]
-		vis := wsFilter(ed, as, bs)
+		vis := wsFilter(ed, as, bs, df.visWS)
 		i1 := i + 1
 		for i1 < len(merged) && (aIsClose(merged, i1) || bIsClose(merged, i1)) {
 			i1++
@@ -113,33 +335,81 @@ func (df *diffTextFormatter) Format(f fmt.State, verb rune) {
 		)
 		for a0 < a1 || b0 < b1 {
 			if a0 < ed.A0 || i > i1 {
-				io.WriteString(f, " ")
-				vis.WriteString(f, as[a0])
-				io.WriteString(f, "\n")
+				df.writeLine(f, vis, " ", as[a0], a0+1, b0+1, width)
 				a0++
 				b0++
 			} else if a0 < ed.A1 {
-				io.WriteString(f, "-")
-				vis.WriteString(f, as[a0])
-				io.WriteString(f, "\n")
+				s := as[a0]
+				if j := ed.B0 + a0 - ed.A0; df.intraLine && j < ed.B1 {
+					s, _ = markChanges(as[a0], bs[j])
+				}
+				df.writeLine(f, vis, "-", s, a0+1, 0, width)
 				a0++
 			} else if b0 < ed.B1 {
-				io.WriteString(f, "+")
-				vis.WriteString(f, bs[b0])
-				io.WriteString(f, "\n")
+				s := bs[b0]
+				if i := ed.A0 + b0 - ed.B0; df.intraLine && i < ed.A1 {
+					_, s = markChanges(as[i], bs[b0])
+				}
+				df.writeLine(f, vis, "+", s, 0, b0+1, width)
 				b0++
 			}
 			if a0 >= ed.A1 && b0 >= ed.B1 {
 				i++
 				if i < len(merged) {
 					ed = merged[i]
-					vis = wsFilter(ed, as, bs)
+					vis = wsFilter(ed, as, bs, df.visWS)
 				}
 			}
 		}
 	}
 }
 
+// markChanges returns a and b with the runes that differ
+// between them enclosed in "⟦" and "⟧", for IntraLineHighlight.
+// If a and b have no runes in common, it returns them as is.
+func markChanges(a, b string) (string, string) {
+	ar := splitRunes(a)
+	br := splitRunes(b)
+	edits := diffseq.DiffSlice(ar, br)
+	if len(edits) == 1 && edits[0] == (diffseq.Edit{A1: len(ar), B1: len(br)}) {
+		return a, b
+	}
+	var am, bm strings.Builder
+	ai, bi := 0, 0
+	for _, ed := range edits {
+		am.WriteString(strings.Join(ar[ai:ed.A0], ""))
+		bm.WriteString(strings.Join(br[bi:ed.B0], ""))
+		if ed.A0 < ed.A1 {
+			am.WriteString("⟦" + strings.Join(ar[ed.A0:ed.A1], "") + "⟧")
+		}
+		if ed.B0 < ed.B1 {
+			bm.WriteString("⟦" + strings.Join(br[ed.B0:ed.B1], "") + "⟧")
+		}
+		ai, bi = ed.A1, ed.B1
+	}
+	am.WriteString(strings.Join(ar[ai:], ""))
+	bm.WriteString(strings.Join(br[bi:], ""))
+	return am.String(), bm.String()
+}
+
+// writeLine writes one line of a unified diff.
+// Line numbers an and bn are 1-based; 0 means none.
+func (df *diffTextFormatter) writeLine(w io.Writer, vis *strings.Replacer, sign, s string, an, bn, width int) {
+	if df.lineNums {
+		fmt.Fprintf(w, "%*s %*s ", width, lineNum(an), width, lineNum(bn))
+	}
+	io.WriteString(w, sign)
+	vis.WriteString(w, s)
+	io.WriteString(w, "\n")
+}
+
+func lineNum(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
 func aIsClose(e []diffseq.Edit, i int) bool { return e[i].A0-e[i-1].A1 <= 2*nContext }
 func bIsClose(e []diffseq.Edit, i int) bool { return e[i].B0-e[i-1].B1 <= 2*nContext }
 
@@ -171,7 +441,15 @@ func splitRunes(s string) (a []string) {
 	return a
 }
 
-func wsFilter(ed diffseq.Edit, as, bs []string) *strings.Replacer {
+func splitBytes(s string) []string {
+	a := make([]string, len(s))
+	for i := range a {
+		a[i] = s[i : i+1]
+	}
+	return a
+}
+
+func wsFilter(ed diffseq.Edit, as, bs []string, visWS *strings.Replacer) *strings.Replacer {
 	if ed.A1-ed.A0 != ed.B1-ed.B0 {
 		return identity
 	}
@@ -25,6 +25,190 @@ func TestTextWSOnly(t *testing.T) {
 	testStringDiff(t, wsonlyMyers, wsonlyA, wsonlyB)
 }
 
+func TestTextWSMarkers(t *testing.T) {
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.WhitespaceMarkers("[space]", "[tab]"), "--- a\n" +
+			"+++ b\n" +
+			"@@ -1,3 +1,3 @@\n" +
+			" x\n" +
+			"-[space][space][space][space]y\n" +
+			"+[tab]y\n" +
+			" z\n\n",
+		},
+		{diff.WhitespaceMarkers(" ", "\t"), "--- a\n" +
+			"+++ b\n" +
+			"@@ -1,3 +1,3 @@\n" +
+			" x\n" +
+			"-    y\n" +
+			"+\ty\n" +
+			" z\n\n",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, wsonlyA, wsonlyB, tt.opt)
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
+
+func TestTextLineNumbers(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a := "x\ny\nz"
+	b := "x\nw\nz\nq"
+	diff.Each(gotp.Printf, a, b, diff.TextLineNumbers())
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		"1 1  x\n" +
+		"2   -y\n" +
+		"  2 +w\n" +
+		"3 3  z\n" +
+		"  4 +q\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTextForce(t *testing.T) {
+	a := []byte("x\ny\xff\nz")
+	b := []byte("x\nw\xff\nz")
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.ForceText())
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-y\ufffd\n" +
+		"+w\ufffd\n" +
+		" z\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, "x\xff", "x\xfe", diff.ForceText())
+	want = `binary: "x\xff" != "x\xfe"` + "\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestTextDecoder(t *testing.T) {
+	latin1 := func(b []byte) (string, bool) {
+		r := make([]rune, len(b))
+		for i, c := range b {
+			if c == 0 {
+				return "", false
+			}
+			r[i] = rune(c)
+		}
+		return string(r), true
+	}
+	a := []byte("x\ncaf\xe9\nz")
+	b := []byte("x\ncaf\xe8\nz")
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.TextDecoder(latin1))
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-café\n" +
+		"+cafè\n" +
+		" z\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, "x\xff\x00", "y\x00", diff.TextDecoder(latin1))
+	want = `binary: "x\xff\x00" != "y\x00"` + "\n"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	// Valid UTF-8 is not decoded.
+	got = ""
+	diff.Each(gotp.Printf, "x\ncafé\nz", "x\ncafè\nz", diff.TextDecoder(latin1))
+	want = "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-café\n" +
+		"+cafè\n" +
+		" z\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	err := diff.Text(&buf, strings.NewReader("x\ny\nz"), strings.NewReader("x\nw\nz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-y\n" +
+		"+w\n" +
+		" z\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	buf.Reset()
+	err = diff.Text(&buf, strings.NewReader("x\ny\nz"), strings.NewReader("x\nw\nz"), diff.Reverse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = buf.String()
+	want = "--- b\n" +
+		"+++ a\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" x\n" +
+		"-w\n" +
+		"+y\n" +
+		" z\n"
+	if got != want {
+		t.Errorf("bad reversed diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	buf.Reset()
+	err = diff.Text(&buf, strings.NewReader("x\n"), strings.NewReader("x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("diff = %q, want empty", buf.String())
+	}
+}
+
 func TestTextWords(t *testing.T) {
 	testStringDiff(t, wordsMyers, wordsA, wordsB)
 }
@@ -37,6 +221,25 @@ func TestTextRunes(t *testing.T) {
 	testStringDiff(t, runesMyers, runesA, runesB)
 }
 
+func TestInlineSegmentLimit(t *testing.T) {
+	cases := []struct {
+		n    int
+		want string
+	}{
+		{0, runesMyers},
+		{3, runesMyers},
+		{2, "\"" + runesA + "\" != \"" + runesB + "\"\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, runesA, runesB, diff.InlineSegmentLimit(tt.n))
+		if got != tt.want {
+			t.Errorf("InlineSegmentLimit(%d) diff = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
 func TestLogMyers(t *testing.T) {
 	var buf bytes.Buffer
 	l := log.New(&buf, "", log.Lshortfile)
@@ -216,3 +419,123 @@ const (
 		"+ \u2192 y\n" +
 		" z\n\n"
 )
+
+func TestBase64Text(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []byte
+		want string
+	}{
+		{"text", []byte("eAp5CnoK"), []byte("eAp3\nCnoK"), "--- a\n" +
+			"+++ b\n" +
+			"@@ -1,3 +1,3 @@\n" +
+			" x\n" +
+			"-y\n" +
+			"+w\n" +
+			" z\n\n"},
+		{"wrapped", []byte("eAp5CnoK"), []byte("eAp5\nCnoK\n"), ""},
+		{"pem", []byte("-----BEGIN X-----\nAAEC\n-----END X-----\n"),
+			[]byte("-----BEGIN X-----\nAAED\n-----END X-----\n"), "--- a\n" +
+				"+++ b\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" -----BEGIN X-----\n" +
+				"-00000000  00 01 02                                          |...|\n" +
+				"+00000000  00 01 03                                          |...|\n" +
+				" -----END X-----\n\n"},
+		{"hex", []byte("\x00\x01!0123456789abcdef"), []byte("\x00\x02!0123456789abcdef"), "--- a\n" +
+			"+++ b\n" +
+			"@@ -1,2 +1,2 @@\n" +
+			"-00000000  00 01 21 30 31 32 33 34  35 36 37 38 39 61 62 63  |..!0123456789abc|\n" +
+			"+00000000  00 02 21 30 31 32 33 34  35 36 37 38 39 61 62 63  |..!0123456789abc|\n" +
+			" 00000010  64 65 66                                          |def|\n\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, diff.Base64Text())
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestContextualStrings(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"abcdefghXijklmnop", "abcdefghYijklmnop", `…cdefgh["X" != "Y"]ijklmn…` + "\n"},
+		{"abXcd", "abYYcd", `ab["X" != "YY"]cd` + "\n"},
+		{"café", "cafè", `caf["\u00e9" != "\u00e8"]` + "\n"},
+		{"ab", "abc", `ab["" != "c"]` + "\n"},
+		{"x", "y", `"x" != "y"` + "\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.ContextualStrings())
+		if got != tt.want {
+			t.Errorf("Each(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestByteChars(t *testing.T) {
+	cases := []struct {
+		a, b []byte
+		want string
+	}{
+		{[]byte("\xffxyA"), []byte("\xffxyB"), "[]uint8[3]: 0x41 'A' != 0x42 'B'\n"},
+		{[]byte("\xff\x00"), []byte("\xff\x01"), "[]uint8[1]: 0x00 != 0x01\n"},
+		{[]byte("\xffab"), []byte("\xffcdb"), `[]uint8[1:2]: "a" != "cd"` + "\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b, diff.ByteChars())
+		if got != tt.want {
+			t.Errorf("Each(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestIntraLineHighlight(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a := "x := foo(1)\ny\nz"
+	b := "x := bar(1)\ny\nz\nq"
+	diff.Each(gotp.Printf, a, b, diff.IntraLineHighlight())
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		"-x := ⟦foo⟧(1)\n" +
+		"+x := ⟦bar⟧(1)\n" +
+		" y\n" +
+		" z\n" +
+		"+q\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	// Lines with nothing in common are unmarked.
+	got = ""
+	diff.Each(gotp.Printf, "a\nb\nc", "a\nX\nc", diff.IntraLineHighlight())
+	want = "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" a\n" +
+		"-b\n" +
+		"+X\n" +
+		" c\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
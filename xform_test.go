@@ -1,6 +1,8 @@
 package diff_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,3 +70,64 @@ func TestNoTimeLoc(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGobEqual(t *testing.T) {
+	type T struct {
+		A int
+		b int // not encoded
+	}
+	diff.Test(t, t.Errorf, T{1, 2}, T{1, 3}, diff.GobEqual[T]())
+
+	equal := true
+	sink := func(format string, arg ...any) {
+		t.Helper()
+		equal = false
+		t.Logf(format, arg...)
+	}
+	diff.Test(t, sink, T{1, 2}, T{2, 2}, diff.GobEqual[T]())
+	if equal {
+		t.Errorf("no diff, want gob diff")
+	}
+
+	type F struct{ F func() }
+	var got []string
+	sink = func(format string, arg ...any) {
+		t.Helper()
+		got = append(got, strings.TrimSpace(fmt.Sprintf(format, arg...)))
+	}
+	diff.Test(t, sink, F{}, F{}, diff.GobEqual[F]())
+	if len(got) != 1 || !strings.HasPrefix(got[0], "diff_test.F(gob): note: encode got: ") {
+		t.Errorf("diff = %q, want encode error", got)
+	}
+
+	// The encode error is not a difference.
+	type U struct{ n int }
+	got = nil
+	diff.Test(t, sink, U{1}, U{2}, diff.GobEqual[U](), diff.Summarize(), diff.FailFast())
+	if len(got) != 3 ||
+		!strings.HasPrefix(got[0], "diff_test.U(gob): note: encode got: ") ||
+		got[1] != "diff_test.U.n: 1 != 2" ||
+		got[2] != "# 1 difference" {
+		t.Errorf("diff = %q, want note, difference, and count", got)
+	}
+}
+
+func TestTransformCache(t *testing.T) {
+	type T struct{ N int }
+	n := 0
+	xf := diff.Transform(func(v T) any {
+		n++
+		return v.N
+	})
+
+	a := make([]T, 100)
+	b := make([]T, 100)
+	for i := range a {
+		a[i].N = i
+		b[i].N = i + 1
+	}
+	diff.Each(func(string, ...any) (int, error) { return 0, nil }, a, b, xf)
+	if max := len(a) + len(b); n > max {
+		t.Errorf("transform called %d times, want at most %d", n, max)
+	}
+}